@@ -0,0 +1,232 @@
+/*
+ * Copyright (c) 2022, AcmeStack
+ * All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package admin 提供动态调整日志级别的http.Handler，可查看及修改Logging及各Logger名称前缀的日志级别，
+// 修改时可指定有效时间（TTL），到期后恢复为修改前的级别
+package admin
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/acmestack/log4go/logfactory"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// LevelInfo 日志级别信息，Name为空表示Logging的日志级别
+type LevelInfo struct {
+	Name  string `json:"name"`
+	Level string `json:"level"`
+	// ExpiresAt 通过TTL修改时，恢复为修改前级别的时间
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+}
+
+// Levels GET请求的返回值
+type Levels struct {
+	Root    LevelInfo   `json:"root"`
+	Loggers []LevelInfo `json:"loggers"`
+}
+
+// LevelRequest PUT请求的请求体
+type LevelRequest struct {
+	// Name Logger名称前缀，为空时修改Logging的日志级别
+	Name string `json:"name"`
+	// Level 日志级别名称，不区分大小写，如：debug
+	Level string `json:"level"`
+	// TTL 有效时间，格式同time.ParseDuration，如：5m，为空时永久有效
+	TTL string `json:"ttl,omitempty"`
+}
+
+// revert 到期恢复的日志级别
+type revert struct {
+	timer     *time.Timer
+	expiresAt time.Time
+	// 修改前的级别，exist为false时表示修改前没有配置该名称前缀
+	level logfactory.Level
+	exist bool
+}
+
+// Handler 动态调整日志级别的http.Handler，支持：
+//
+//	GET：返回Levels
+//	PUT：请求体为LevelRequest，返回修改后的Levels
+//	DELETE：删除参数name对应名称前缀的日志级别，返回修改后的Levels
+type Handler struct {
+	factory logfactory.LoggerFactoryI
+	lock    sync.Mutex
+	reverts map[string]*revert
+}
+
+// NewHandler 创建Handler，操作factory的Logging（通过GetLogging获得，Reset后操作新的Logging）
+func NewHandler(factory logfactory.LoggerFactoryI) *Handler {
+	return &Handler{
+		factory: factory,
+		reverts: map[string]*revert{},
+	}
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		req := LevelRequest{}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, fmt.Sprintf("invalid request: %v", err), http.StatusBadRequest)
+			return
+		}
+		if err := h.SetLevel(req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	case http.MethodDelete:
+		name := r.URL.Query().Get("name")
+		if name == "" {
+			http.Error(w, "name is required", http.StatusBadRequest)
+			return
+		}
+		h.RemoveLevel(name)
+	default:
+		w.Header().Set("Allow", "GET, PUT, DELETE")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(h.Levels())
+}
+
+// Levels 获得Logging及所有名称前缀的日志级别，名称前缀按名称排序
+func (h *Handler) Levels() Levels {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	logging := h.factory.GetLogging()
+	ret := Levels{
+		Root: h.levelInfo("", logging.GetLogLevel()),
+	}
+	for name, level := range logging.GetLogLevelsByName() {
+		ret.Loggers = append(ret.Loggers, h.levelInfo(name, level))
+	}
+	sort.Slice(ret.Loggers, func(i, j int) bool {
+		return ret.Loggers[i].Name < ret.Loggers[j].Name
+	})
+	return ret
+}
+
+func (h *Handler) levelInfo(name string, level logfactory.Level) LevelInfo {
+	ret := LevelInfo{
+		Name:  name,
+		Level: logfactory.LogTag[level],
+	}
+	if r, ok := h.reverts[name]; ok {
+		expiresAt := r.expiresAt
+		ret.ExpiresAt = &expiresAt
+	}
+	return ret
+}
+
+// SetLevel 修改日志级别，指定TTL时到期后恢复为第一次通过TTL修改前的级别，未指定TTL时取消之前的恢复
+func (h *Handler) SetLevel(req LevelRequest) error {
+	level, err := logfactory.ParseLevel(req.Level)
+	if err != nil {
+		return err
+	}
+	var ttl time.Duration
+	if req.TTL != "" {
+		if ttl, err = time.ParseDuration(req.TTL); err != nil {
+			return fmt.Errorf("invalid ttl: %w", err)
+		}
+		if ttl <= 0 {
+			return fmt.Errorf("invalid ttl: %s", req.TTL)
+		}
+	}
+
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	logging := h.factory.GetLogging()
+	r, ok := h.reverts[req.Name]
+	if ok {
+		r.timer.Stop()
+		delete(h.reverts, req.Name)
+	}
+	if ttl > 0 {
+		// 每次使用新的revert，已触发但等待锁的旧timer不会误恢复新的修改
+		next := &revert{}
+		if ok {
+			next.level, next.exist = r.level, r.exist
+		} else {
+			next.level, next.exist = currentLevel(logging, req.Name)
+		}
+		next.expiresAt = time.Now().Add(ttl)
+		next.timer = time.AfterFunc(ttl, func() {
+			h.expire(req.Name, next)
+		})
+		h.reverts[req.Name] = next
+	}
+	logging.SetLogLevelByName(req.Name, level)
+	return nil
+}
+
+// RemoveLevel 删除名称前缀的日志级别，并取消到期恢复
+func (h *Handler) RemoveLevel(name string) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	if r, ok := h.reverts[name]; ok {
+		r.timer.Stop()
+		delete(h.reverts, name)
+	}
+	h.factory.GetLogging().RemoveLogLevelByName(name)
+}
+
+// Stop 取消所有未到期的恢复，修改后的级别将保持不变
+func (h *Handler) Stop() {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	for name, r := range h.reverts {
+		r.timer.Stop()
+		delete(h.reverts, name)
+	}
+}
+
+func (h *Handler) expire(name string, r *revert) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	// 已被再次修改
+	if h.reverts[name] != r {
+		return
+	}
+	delete(h.reverts, name)
+	logging := h.factory.GetLogging()
+	if r.exist {
+		logging.SetLogLevelByName(name, r.level)
+	} else {
+		logging.RemoveLogLevelByName(name)
+	}
+}
+
+func currentLevel(logging logfactory.Logging, name string) (logfactory.Level, bool) {
+	if name == "" {
+		return logging.GetLogLevel(), true
+	}
+	level, ok := logging.GetLogLevelsByName()[name]
+	return level, ok
+}
@@ -0,0 +1,337 @@
+/*
+ * Copyright (c) 2022, AcmeStack
+ * All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package appender
+
+import (
+	"github.com/acmestack/log4go/logfactory"
+	"github.com/acmestack/log4go/util"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultSize 每个级别默认保存的记录数量
+	DefaultSize = 1000
+	// maxPendingGroups 等待ERROR触发输出的最大分组数量，超出时丢弃最早的分组
+	maxPendingGroups = 1024
+)
+
+// Record 结构化的日志记录
+type Record struct {
+	Time    time.Time
+	Level   logfactory.Level
+	Name    string
+	Caller  string
+	Content string
+	// 完整的日志信息，包括内置的LogTime、LogLevel等
+	KeyValues util.KeyValues
+	// 格式化后的日志
+	Data []byte
+
+	seq uint64
+}
+
+// Query 查询条件，零值表示不限制
+type Query struct {
+	// 日志级别
+	Levels []logfactory.Level
+	// Logger名称前缀，以"."分隔，如a.b匹配a.b及a.b.c，不匹配a.bc
+	Name string
+	// 时间范围[Since, Until)
+	Since time.Time
+	Until time.Time
+	// 字段值，按格式化后的字符串比较
+	Fields map[string]interface{}
+}
+
+type MemoryOpt func(a *MemoryAppender)
+
+// FlushOnError 不输出DEBUG级别日志，而是按key字段（为空时使用Logger名称）分组缓存，
+// 当同组出现ERROR及更严重级别的日志时，先输出缓存的DEBUG日志再输出该日志
+func FlushOnError(key string) MemoryOpt {
+	return func(a *MemoryAppender) {
+		a.flushOnError = true
+		a.groupKey = key
+	}
+}
+
+// MemoryAppender 在内存中保存每个级别最近的日志记录，用于问题排查。
+// 实现了logfactory.RecordWriter，作为Logging的输出Writer，记录后按日志级别写入实际的Writer，与Logging是否配置Formatter无关：
+// logging.SetOutput(appender.NewMemoryAppender(100, os.Stdout))
+type MemoryAppender struct {
+	lock  sync.Mutex
+	size  int
+	out   io.Writer
+	rings map[logfactory.Level]*ring
+	seq   uint64
+
+	flushOnError bool
+	groupKey     string
+	pending      map[string][]Record
+	groups       []string
+}
+
+// levelWriter 可感知日志级别的Writer，如writer.LevelRouter
+type levelWriter interface {
+	WriteLevel(level logfactory.Level, data []byte) (int, error)
+}
+
+// NewMemoryAppender Param: size - 每个级别保存的记录数量，
+// out - 实际写入的Writer，为nil时只记录不输出，实现了WriteLevel（如writer.LevelRouter）时按记录的级别写入
+func NewMemoryAppender(size int, out io.Writer, opts ...MemoryOpt) *MemoryAppender {
+	if size <= 0 {
+		size = DefaultSize
+	}
+	ret := &MemoryAppender{
+		size:    size,
+		out:     out,
+		rings:   map[logfactory.Level]*ring{},
+		pending: map[string][]Record{},
+	}
+	for _, v := range opts {
+		v(ret)
+	}
+	return ret
+}
+
+// WriteRecord 实现logfactory.RecordWriter，记录日志并写入实际的Writer
+func (a *MemoryAppender) WriteRecord(level logfactory.Level, record util.KeyValues, data []byte) (int, error) {
+	r := a.newRecord(level, record, data)
+
+	a.lock.Lock()
+	a.seq++
+	r.seq = a.seq
+	rg, ok := a.rings[r.Level]
+	if !ok {
+		rg = newRing(a.size)
+		a.rings[r.Level] = rg
+	}
+	rg.add(r)
+
+	var flush []Record
+	if a.flushOnError {
+		group := a.group(r)
+		if r.Level >= logfactory.DEBUG {
+			a.addPending(group, r)
+			a.lock.Unlock()
+			return len(data), nil
+		}
+		if r.Level <= logfactory.ERROR {
+			flush = a.pending[group]
+			a.removePending(group)
+		}
+	}
+	a.lock.Unlock()
+
+	for _, v := range flush {
+		if _, err := a.output(v.Level, v.Data); err != nil {
+			return 0, err
+		}
+	}
+	return a.output(level, data)
+}
+
+// Write 直接写入实际的Writer，不记录
+func (a *MemoryAppender) Write(data []byte) (int, error) {
+	if a.out == nil {
+		return len(data), nil
+	}
+	return a.out.Write(data)
+}
+
+// Flush 实际的Writer实现了Flush时调用其Flush，用于Logging.Flush
+func (a *MemoryAppender) Flush() error {
+	if f, ok := a.out.(interface{ Flush() error }); ok {
+		return f.Flush()
+	}
+	return nil
+}
+
+// Close 实际的Writer实现了io.Closer时将其关闭，用于Logging.Close
+func (a *MemoryAppender) Close() error {
+	if c, ok := a.out.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// output 按日志级别写入实际的Writer
+func (a *MemoryAppender) output(level logfactory.Level, data []byte) (int, error) {
+	if a.out == nil {
+		return len(data), nil
+	}
+	if lw, ok := a.out.(levelWriter); ok {
+		return lw.WriteLevel(level, data)
+	}
+	return a.out.Write(data)
+}
+
+// Records 获得符合条件的日志记录，按记录的先后顺序排列
+func (a *MemoryAppender) Records(q Query) []Record {
+	a.lock.Lock()
+	var ret []Record
+	for level, rg := range a.rings {
+		if !q.matchLevel(level) {
+			continue
+		}
+		rg.each(func(r Record) {
+			if q.match(r) {
+				ret = append(ret, r)
+			}
+		})
+	}
+	a.lock.Unlock()
+
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].seq < ret[j].seq
+	})
+	return ret
+}
+
+// Dump 将符合条件的日志记录格式化后的内容输出到writer
+func (a *MemoryAppender) Dump(writer io.Writer, q Query) error {
+	for _, r := range a.Records(q) {
+		if _, err := writer.Write(r.Data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Reset 清空所有记录
+func (a *MemoryAppender) Reset() {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	a.rings = map[logfactory.Level]*ring{}
+	a.pending = map[string][]Record{}
+	a.groups = nil
+}
+
+func (a *MemoryAppender) newRecord(level logfactory.Level, keyValues util.KeyValues, data []byte) Record {
+	kvs := keyValues.Clone()
+	r := Record{
+		Level:     level,
+		Name:      util.FormatValue(kvs.Get(logfactory.NameKey), false),
+		Caller:    util.FormatValue(kvs.Get(logfactory.CallerKey), false),
+		Content:   strings.TrimSuffix(util.FormatValue(kvs.Get(logfactory.ContentKey), false), "\n"),
+		KeyValues: kvs,
+		Data:      append([]byte(nil), data...),
+	}
+	if t, ok := kvs.Get(logfactory.TimestampKey).(time.Time); ok {
+		r.Time = t
+	}
+	return r
+}
+
+func (a *MemoryAppender) group(r Record) string {
+	if a.groupKey == "" {
+		return r.Name
+	}
+	return util.FormatValue(r.KeyValues.Get(a.groupKey), false)
+}
+
+func (a *MemoryAppender) addPending(group string, r Record) {
+	records, ok := a.pending[group]
+	if !ok {
+		if len(a.groups) >= maxPendingGroups {
+			a.removePending(a.groups[0])
+		}
+		a.groups = append(a.groups, group)
+	}
+	if len(records) >= a.size {
+		records = records[1:]
+	}
+	a.pending[group] = append(records, r)
+}
+
+func (a *MemoryAppender) removePending(group string) {
+	if _, ok := a.pending[group]; !ok {
+		return
+	}
+	delete(a.pending, group)
+	for i, v := range a.groups {
+		if v == group {
+			a.groups = append(a.groups[:i:i], a.groups[i+1:]...)
+			break
+		}
+	}
+}
+
+func (q *Query) matchLevel(level logfactory.Level) bool {
+	if len(q.Levels) == 0 {
+		return true
+	}
+	for _, v := range q.Levels {
+		if v == level {
+			return true
+		}
+	}
+	return false
+}
+
+func (q *Query) match(r Record) bool {
+	if q.Name != "" && !logfactory.MatchName(r.Name, q.Name) {
+		return false
+	}
+	if !q.Since.IsZero() && r.Time.Before(q.Since) {
+		return false
+	}
+	if !q.Until.IsZero() && !r.Time.Before(q.Until) {
+		return false
+	}
+	for k, v := range q.Fields {
+		if util.FormatValue(r.KeyValues.Get(k), false) != util.FormatValue(v, false) {
+			return false
+		}
+	}
+	return true
+}
+
+type ring struct {
+	records []Record
+	next    int
+	full    bool
+}
+
+func newRing(size int) *ring {
+	return &ring{records: make([]Record, size)}
+}
+
+func (r *ring) add(record Record) {
+	r.records[r.next] = record
+	r.next++
+	if r.next == len(r.records) {
+		r.next = 0
+		r.full = true
+	}
+}
+
+func (r *ring) each(f func(Record)) {
+	if r.full {
+		for _, v := range r.records[r.next:] {
+			f(v)
+		}
+	}
+	for _, v := range r.records[:r.next] {
+		f(v)
+	}
+}
@@ -18,15 +18,17 @@
 package ext
 
 import (
+	"context"
 	"github.com/acmestack/log4go/logfactory"
 	"github.com/acmestack/log4go/util"
 )
 
 type mutableLog struct {
-	logging util.Value
+	logging util.TypedValue[logfactory.Logging]
 	depth   int
 	fields  util.KeyValues
 	name    string
+	group   string
 }
 
 type mutableLoggerFactory struct {
@@ -34,10 +36,10 @@ type mutableLoggerFactory struct {
 }
 
 func NewMutableFactory(logging logfactory.Logging) *mutableLoggerFactory {
-	return NewMutableFactoryWithValue(util.NewAtomicValue(logging))
+	return NewMutableFactoryWithValue(util.NewAtomic[logfactory.Logging](logging))
 }
 
-func NewMutableFactoryWithValue(v util.Value) *mutableLoggerFactory {
+func NewMutableFactoryWithValue(v util.TypedValue[logfactory.Logging]) *mutableLoggerFactory {
 	ret := &mutableLoggerFactory{}
 	ret.Value = v
 	return ret
@@ -48,14 +50,15 @@ func (fac *mutableLoggerFactory) GetLogger(o ...interface{}) logfactory.Logger {
 	return newMutableLogger(fac.Value, nil, name)
 }
 
-func newMutableLogger(loggingValue util.Value, fields util.KeyValues, name ...string) *mutableLog {
+func newMutableLogger(loggingValue util.TypedValue[logfactory.Logging], fields util.KeyValues, name ...string) *mutableLog {
 	if fields == nil {
 		fields = util.NewKeyValues()
 	}
 	var t string
 	if len(name) > 0 {
 		t = name[0]
-		if t != "" {
+		// 名称未变化时不修改，避免复制共享的附加信息
+		if t != "" && fields.Get(logfactory.NameKey) != t {
 			fields.Add(logfactory.NameKey, t)
 		}
 	}
@@ -69,11 +72,11 @@ func newMutableLogger(loggingValue util.Value, fields util.KeyValues, name ...st
 }
 
 func (l *mutableLog) getLogging() logfactory.Logging {
-	return l.logging.Load().(logfactory.Logging)
+	return l.logging.Load()
 }
 
 func (l *mutableLog) DebugEnabled() bool {
-	return l.getLogging().IsEnabled(logfactory.DEBUG)
+	return l.IsEnabled(logfactory.DEBUG)
 }
 
 func (l *mutableLog) Debug(args ...interface{}) {
@@ -88,8 +91,12 @@ func (l *mutableLog) DebugF(fmt string, args ...interface{}) {
 	l.getLogging().LogF(logfactory.DEBUG, l.depth, l.fields, fmt, args...)
 }
 
+func (l *mutableLog) DebugT(template string, args ...interface{}) {
+	l.getLogging().LogT(logfactory.DEBUG, l.depth, l.fields, template, args...)
+}
+
 func (l *mutableLog) InfoEnabled() bool {
-	return l.getLogging().IsEnabled(logfactory.INFO)
+	return l.IsEnabled(logfactory.INFO)
 }
 
 func (l *mutableLog) Info(args ...interface{}) {
@@ -104,8 +111,12 @@ func (l *mutableLog) InfoF(fmt string, args ...interface{}) {
 	l.getLogging().LogF(logfactory.INFO, l.depth, l.fields, fmt, args...)
 }
 
+func (l *mutableLog) InfoT(template string, args ...interface{}) {
+	l.getLogging().LogT(logfactory.INFO, l.depth, l.fields, template, args...)
+}
+
 func (l *mutableLog) WarnEnabled() bool {
-	return l.getLogging().IsEnabled(logfactory.WARN)
+	return l.IsEnabled(logfactory.WARN)
 }
 
 func (l *mutableLog) Warn(args ...interface{}) {
@@ -120,8 +131,12 @@ func (l *mutableLog) WarnF(fmt string, args ...interface{}) {
 	l.getLogging().LogF(logfactory.WARN, l.depth, l.fields, fmt, args...)
 }
 
+func (l *mutableLog) WarnT(template string, args ...interface{}) {
+	l.getLogging().LogT(logfactory.WARN, l.depth, l.fields, template, args...)
+}
+
 func (l *mutableLog) ErrorEnabled() bool {
-	return l.getLogging().IsEnabled(logfactory.ERROR)
+	return l.IsEnabled(logfactory.ERROR)
 }
 
 func (l *mutableLog) Error(args ...interface{}) {
@@ -136,8 +151,12 @@ func (l *mutableLog) ErrorF(fmt string, args ...interface{}) {
 	l.getLogging().LogF(logfactory.ERROR, l.depth, l.fields, fmt, args...)
 }
 
+func (l *mutableLog) ErrorT(template string, args ...interface{}) {
+	l.getLogging().LogT(logfactory.ERROR, l.depth, l.fields, template, args...)
+}
+
 func (l *mutableLog) PanicEnabled() bool {
-	return l.getLogging().IsEnabled(logfactory.PANIC)
+	return l.IsEnabled(logfactory.PANIC)
 }
 
 func (l *mutableLog) Panic(args ...interface{}) {
@@ -152,8 +171,12 @@ func (l *mutableLog) PanicF(fmt string, args ...interface{}) {
 	l.getLogging().LogF(logfactory.PANIC, l.depth, l.fields, fmt, args...)
 }
 
+func (l *mutableLog) PanicT(template string, args ...interface{}) {
+	l.getLogging().LogT(logfactory.PANIC, l.depth, l.fields, template, args...)
+}
+
 func (l *mutableLog) FatalEnabled() bool {
-	return l.getLogging().IsEnabled(logfactory.FATAL)
+	return l.IsEnabled(logfactory.FATAL)
 }
 
 func (l *mutableLog) Fatal(args ...interface{}) {
@@ -168,8 +191,32 @@ func (l *mutableLog) FatalF(fmt string, args ...interface{}) {
 	l.getLogging().LogF(logfactory.FATAL, l.depth, l.fields, fmt, args...)
 }
 
+func (l *mutableLog) FatalT(template string, args ...interface{}) {
+	l.getLogging().LogT(logfactory.FATAL, l.depth, l.fields, template, args...)
+}
+
+func (l *mutableLog) RecoverAndLog() {
+	if v := recover(); v != nil {
+		l.LogRecovered(v)
+	}
+}
+
+func (l *mutableLog) LogRecovered(v interface{}) {
+	logfactory.LogRecovered(l.getLogging(), l.fields, v)
+}
+
+func (l *mutableLog) Go(f func()) {
+	go func() {
+		defer l.RecoverAndLog()
+		f()
+	}()
+}
+
 func (l *mutableLog) IsEnabled(severityLevel logfactory.Level) bool {
-	return l.getLogging().IsEnabled(severityLevel)
+	if logfactory.ForceEnabled(l.fields, severityLevel) {
+		return true
+	}
+	return l.getLogging().IsEnabledByName(l.name, severityLevel)
 }
 
 func (l *mutableLog) WithName(name string) logfactory.Logger {
@@ -183,6 +230,7 @@ func (l *mutableLog) WithName(name string) logfactory.Logger {
 	ret := newMutableLogger(l.logging, l.fields.Clone(), name)
 	ret.fields.Add(logfactory.NameKey, ret.name)
 	ret.depth = l.depth
+	ret.group = l.group
 
 	return ret
 }
@@ -192,8 +240,9 @@ func (l *mutableLog) WithFields(keyAndValues ...interface{}) logfactory.Logger {
 		return nil
 	}
 	ret := newMutableLogger(l.logging, l.fields.Clone(), l.name)
-	ret.fields.Add(keyAndValues...)
+	ret.fields.Add(logfactory.GroupFields(l.group, keyAndValues...)...)
 	ret.depth = l.depth
+	ret.group = l.group
 
 	return ret
 }
@@ -204,6 +253,48 @@ func (l *mutableLog) WithDepth(depth int) logfactory.Logger {
 	}
 	ret := newMutableLogger(l.logging, l.fields.Clone(), l.name)
 	ret.depth += depth
+	ret.group = l.group
+
+	return ret
+}
+
+func (l *mutableLog) WithStack() logfactory.Logger {
+	if l == nil {
+		return nil
+	}
+	ret := newMutableLogger(l.logging, l.fields.Clone(), l.name)
+	ret.fields.Add(logfactory.StackKey, logfactory.StackCurrent)
+	ret.depth = l.depth
+	ret.group = l.group
+
+	return ret
+}
+
+func (l *mutableLog) WithContext(ctx context.Context) logfactory.Logger {
+	if l == nil {
+		return nil
+	}
+	fields := logfactory.ContextFields(ctx, l.fields)
+	if fields == l.fields {
+		return l
+	}
+	ret := newMutableLogger(l.logging, fields, l.name)
+	ret.depth = l.depth
+	ret.group = l.group
+
+	return ret
+}
+
+func (l *mutableLog) WithGroup(name string) logfactory.Logger {
+	if l == nil {
+		return nil
+	}
+	if name == "" {
+		return l
+	}
+	ret := newMutableLogger(l.logging, l.fields.Clone(), l.name)
+	ret.depth = l.depth
+	ret.group = logfactory.GroupName(l.group, name)
 
 	return ret
 }
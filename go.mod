@@ -1,3 +1,3 @@
 module github.com/acmestack/log4go
 
-go 1.18
\ No newline at end of file
+go 1.19
\ No newline at end of file
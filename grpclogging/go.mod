@@ -0,0 +1,19 @@
+module github.com/acmestack/log4go/grpclogging
+
+go 1.19
+
+require (
+	github.com/acmestack/log4go v0.0.0-20261019002339-dce38929fe44
+	google.golang.org/grpc v1.64.0
+	google.golang.org/protobuf v1.33.0
+)
+
+require (
+	golang.org/x/net v0.22.0 // indirect
+	golang.org/x/sys v0.18.0 // indirect
+	golang.org/x/text v0.14.0 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20240318140521-94a12d6c2237 // indirect
+)
+
+// 本地开发时使用仓库中的根模块，发布时去掉replace以使用上面require的版本
+replace github.com/acmestack/log4go => ../
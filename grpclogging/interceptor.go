@@ -0,0 +1,319 @@
+/*
+ * Copyright (c) 2022, AcmeStack
+ * All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package grpclogging 提供通过log4go Logger输出gRPC调用日志的服务端及客户端拦截器，
+// 为独立的module，避免log4go依赖gRPC
+package grpclogging
+
+import (
+	"context"
+	"github.com/acmestack/log4go/logfactory"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 调用日志的字段
+const (
+	ServiceKey  = "grpc.service"
+	MethodKey   = "grpc.method"
+	KindKey     = "grpc.kind"
+	CodeKey     = "grpc.code"
+	DurationKey = "grpc.duration"
+	PeerKey     = "peer.address"
+	// SentKey 发送的消息数，RecvKey 接收的消息数
+	SentKey = "grpc.sent_messages"
+	RecvKey = "grpc.recv_messages"
+	// SentBytesKey 发送消息的字节数，RecvBytesKey 接收消息的字节数（proto.Size）
+	SentBytesKey = "grpc.sent_bytes"
+	RecvBytesKey = "grpc.recv_bytes"
+)
+
+// 调用类型
+const (
+	KindUnary  = "unary"
+	KindStream = "stream"
+)
+
+// Config 拦截器的配置
+type Config struct {
+	// Logger 输出调用日志的Logger，为nil时使用logfactory.GetLogger("grpc")
+	Logger logfactory.Logger
+	// LevelFunc 根据状态码获得日志级别，为nil时使用DefaultLevel
+	LevelFunc func(code codes.Code) logfactory.Level
+	// Skip 返回true的方法不输出调用日志（仍会注入调用的Logger），参数为完整方法名，如：/grpc.health.v1.Health/Check
+	Skip func(fullMethod string) bool
+}
+
+// DefaultLevel 默认的日志级别：OK为INFO，调用方导致的错误为WARN，其他为ERROR
+func DefaultLevel(code codes.Code) logfactory.Level {
+	switch code {
+	case codes.OK:
+		return logfactory.INFO
+	case codes.Canceled, codes.InvalidArgument, codes.NotFound, codes.AlreadyExists, codes.PermissionDenied,
+		codes.Unauthenticated, codes.FailedPrecondition, codes.OutOfRange, codes.ResourceExhausted:
+		return logfactory.WARN
+	default:
+		return logfactory.ERROR
+	}
+}
+
+func (c Config) init() Config {
+	if c.Logger == nil {
+		c.Logger = logfactory.GetLogger("grpc")
+	}
+	if c.LevelFunc == nil {
+		c.LevelFunc = DefaultLevel
+	}
+	return c
+}
+
+type loggerCtxKey struct{}
+
+// NewContext 返回携带logger的ctx
+func NewContext(ctx context.Context, logger logfactory.Logger) context.Context {
+	return context.WithValue(ctx, loggerCtxKey{}, logger)
+}
+
+// FromContext 获得ctx携带的Logger（拦截器注入的调用Logger附加了服务、方法及对端地址），没有时返回logfactory.GetLogger()
+func FromContext(ctx context.Context) logfactory.Logger {
+	if logger, ok := ctx.Value(loggerCtxKey{}).(logfactory.Logger); ok {
+		return logger
+	}
+	return logfactory.GetLogger().WithContext(ctx)
+}
+
+// call 一次调用的统计信息，客户端流的收发可能在不同协程中，计数使用原子操作
+type call struct {
+	config    Config
+	logger    logfactory.Logger
+	method    string
+	start     time.Time
+	sent      int64
+	recv      int64
+	sentBytes int64
+	recvBytes int64
+}
+
+func newCall(ctx context.Context, config Config, fullMethod string, kind string) (*call, context.Context) {
+	service, method := splitMethod(fullMethod)
+	fields := []interface{}{ServiceKey, service, MethodKey, method, KindKey, kind}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		fields = append(fields, PeerKey, p.Addr.String())
+	}
+	c := &call{
+		config: config,
+		logger: config.Logger.WithContext(ctx).WithFields(fields...),
+		method: fullMethod,
+		start:  time.Now(),
+	}
+	return c, NewContext(ctx, c.logger)
+}
+
+func (c *call) onSend(msg interface{}) {
+	atomic.AddInt64(&c.sent, 1)
+	atomic.AddInt64(&c.sentBytes, int64(messageSize(msg)))
+}
+
+func (c *call) onRecv(msg interface{}) {
+	atomic.AddInt64(&c.recv, 1)
+	atomic.AddInt64(&c.recvBytes, int64(messageSize(msg)))
+}
+
+func (c *call) finish(err error) {
+	if c.config.Skip != nil && c.config.Skip(c.method) {
+		return
+	}
+	code := status.Code(err)
+	logger := c.logger.WithFields(
+		CodeKey, code.String(),
+		DurationKey, time.Since(c.start),
+		SentKey, int(atomic.LoadInt64(&c.sent)),
+		RecvKey, int(atomic.LoadInt64(&c.recv)),
+		SentBytesKey, int(atomic.LoadInt64(&c.sentBytes)),
+		RecvBytesKey, int(atomic.LoadInt64(&c.recvBytes)))
+	if err != nil {
+		logger = logger.WithFields(logfactory.ErrorKey, logfactory.Err(err))
+	}
+	switch c.config.LevelFunc(code) {
+	case logfactory.DEBUG:
+		logger.DebugLn("finished call", c.method)
+	case logfactory.INFO:
+		logger.InfoLn("finished call", c.method)
+	case logfactory.WARN:
+		logger.WarnLn("finished call", c.method)
+	default:
+		// 调用日志不应触发panic或退出，PANIC、FATAL按ERROR输出
+		logger.ErrorLn("finished call", c.method)
+	}
+}
+
+// UnaryServerInterceptor 输出一元调用日志的服务端拦截器，并向ctx注入调用的Logger，可通过FromContext获得
+func UnaryServerInterceptor(config Config) grpc.UnaryServerInterceptor {
+	config = config.init()
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		c, ctx := newCall(ctx, config, info.FullMethod, KindUnary)
+		c.onRecv(req)
+		resp, err := handler(ctx, req)
+		if err == nil {
+			c.onSend(resp)
+		}
+		c.finish(err)
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor 输出流式调用日志的服务端拦截器，并向流的ctx注入调用的Logger，可通过FromContext获得
+func StreamServerInterceptor(config Config) grpc.StreamServerInterceptor {
+	config = config.init()
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		c, ctx := newCall(ss.Context(), config, info.FullMethod, KindStream)
+		err := handler(srv, &serverStream{ServerStream: ss, ctx: ctx, call: c})
+		c.finish(err)
+		return err
+	}
+}
+
+// UnaryClientInterceptor 输出一元调用日志的客户端拦截器
+func UnaryClientInterceptor(config Config) grpc.UnaryClientInterceptor {
+	config = config.init()
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		c, ctx := newCall(ctx, config, method, KindUnary)
+		c.logger = c.logger.WithFields(PeerKey, cc.Target())
+		c.onSend(req)
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		if err == nil {
+			c.onRecv(reply)
+		}
+		c.finish(err)
+		return err
+	}
+}
+
+// StreamClientInterceptor 输出流式调用日志的客户端拦截器，调用在以下情况结束：
+// RecvMsg返回错误（包括io.EOF）、非服务端流的调用RecvMsg接收到响应、ctx取消或超时
+func StreamClientInterceptor(config Config) grpc.StreamClientInterceptor {
+	config = config.init()
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		c, ctx := newCall(ctx, config, method, KindStream)
+		c.logger = c.logger.WithFields(PeerKey, cc.Target())
+		cs, err := streamer(ctx, desc, cc, method, opts...)
+		if err != nil {
+			c.finish(err)
+			return nil, err
+		}
+		s := &clientStream{ClientStream: cs, desc: desc, call: c, done: make(chan struct{})}
+		go func() {
+			select {
+			case <-ctx.Done():
+				s.finish(status.FromContextError(ctx.Err()).Err())
+			case <-s.done:
+			}
+		}()
+		return s, nil
+	}
+}
+
+type serverStream struct {
+	grpc.ServerStream
+	ctx  context.Context
+	call *call
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *serverStream) SendMsg(m interface{}) error {
+	err := s.ServerStream.SendMsg(m)
+	if err == nil {
+		s.call.onSend(m)
+	}
+	return err
+}
+
+func (s *serverStream) RecvMsg(m interface{}) error {
+	err := s.ServerStream.RecvMsg(m)
+	if err == nil {
+		s.call.onRecv(m)
+	}
+	return err
+}
+
+type clientStream struct {
+	grpc.ClientStream
+	desc *grpc.StreamDesc
+	call *call
+	once sync.Once
+	done chan struct{}
+}
+
+func (s *clientStream) SendMsg(m interface{}) error {
+	err := s.ClientStream.SendMsg(m)
+	if err == nil {
+		s.call.onSend(m)
+	}
+	return err
+}
+
+func (s *clientStream) RecvMsg(m interface{}) error {
+	err := s.ClientStream.RecvMsg(m)
+	if err == nil {
+		s.call.onRecv(m)
+		// 非服务端流只有一个响应，接收后调用即结束
+		if !s.desc.ServerStreams {
+			s.finish(nil)
+		}
+		return nil
+	}
+	if err == io.EOF {
+		s.finish(nil)
+	} else {
+		s.finish(err)
+	}
+	return err
+}
+
+// finish 结束调用并输出调用日志，只执行一次
+func (s *clientStream) finish(err error) {
+	s.once.Do(func() {
+		close(s.done)
+		s.call.finish(err)
+	})
+}
+
+func splitMethod(fullMethod string) (string, string) {
+	fullMethod = strings.TrimPrefix(fullMethod, "/")
+	if i := strings.LastIndex(fullMethod, "/"); i >= 0 {
+		return fullMethod[:i], fullMethod[i+1:]
+	}
+	return "unknown", fullMethod
+}
+
+func messageSize(msg interface{}) int {
+	if m, ok := msg.(proto.Message); ok {
+		return proto.Size(m)
+	}
+	return 0
+}
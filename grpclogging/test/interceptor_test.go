@@ -0,0 +1,198 @@
+/*
+ * Copyright (c) 2022, AcmeStack
+ * All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package test
+
+import (
+	"context"
+	"github.com/acmestack/log4go/grpclogging"
+	"github.com/acmestack/log4go/logfactory"
+	"github.com/acmestack/log4go/logtest"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+type healthServer struct {
+	*health.Server
+}
+
+func (s *healthServer) Check(ctx context.Context, req *grpc_health_v1.HealthCheckRequest) (*grpc_health_v1.HealthCheckResponse, error) {
+	grpclogging.FromContext(ctx).Info("checking ", req.Service)
+	return s.Server.Check(ctx, req)
+}
+
+// sumDesc 客户端流的求和方法，接收多个Int64Value，返回它们的和
+var sumDesc = grpc.StreamDesc{
+	StreamName:    "Sum",
+	ClientStreams: true,
+	Handler: func(srv interface{}, ss grpc.ServerStream) error {
+		var sum int64
+		for {
+			v := &wrapperspb.Int64Value{}
+			err := ss.RecvMsg(v)
+			if err == io.EOF {
+				return ss.SendMsg(wrapperspb.Int64(sum))
+			}
+			if err != nil {
+				return err
+			}
+			sum += v.Value
+		}
+	},
+}
+
+func startServer(t *testing.T, server, client *logtest.Recorder) *grpc.ClientConn {
+	lis := bufconn.Listen(1024 * 1024)
+	s := grpc.NewServer(
+		grpc.UnaryInterceptor(grpclogging.UnaryServerInterceptor(grpclogging.Config{Logger: server.Logger("server")})),
+		grpc.StreamInterceptor(grpclogging.StreamServerInterceptor(grpclogging.Config{Logger: server.Logger("server")})))
+	hs := &healthServer{Server: health.NewServer()}
+	hs.SetServingStatus("db", grpc_health_v1.HealthCheckResponse_SERVING)
+	grpc_health_v1.RegisterHealthServer(s, hs)
+	s.RegisterService(&grpc.ServiceDesc{
+		ServiceName: "test.Calculator",
+		HandlerType: (*interface{})(nil),
+		Streams:     []grpc.StreamDesc{sumDesc},
+	}, struct{}{})
+	go func() {
+		_ = s.Serve(lis)
+	}()
+	t.Cleanup(s.Stop)
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithUnaryInterceptor(grpclogging.UnaryClientInterceptor(grpclogging.Config{Logger: client.Logger("client")})),
+		grpc.WithStreamInterceptor(grpclogging.StreamClientInterceptor(grpclogging.Config{Logger: client.Logger("client")})))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = conn.Close()
+	})
+	return conn
+}
+
+func TestUnaryInterceptors(t *testing.T) {
+	server, client := logtest.New(t), logtest.New(t)
+	hc := grpc_health_v1.NewHealthClient(startServer(t, server, client))
+
+	if _, err := hc.Check(context.Background(), &grpc_health_v1.HealthCheckRequest{Service: "db"}); err != nil {
+		t.Fatal(err)
+	}
+	_, err := hc.Check(context.Background(), &grpc_health_v1.HealthCheckRequest{Service: "cache"})
+	if status.Code(err) != codes.NotFound {
+		t.Fatalf("expect not found, but get %v", err)
+	}
+
+	fields := []interface{}{grpclogging.ServiceKey, "grpc.health.v1.Health", grpclogging.MethodKey, "Check",
+		grpclogging.KindKey, grpclogging.KindUnary}
+	server.AssertContains(logfactory.INFO, append(fields, grpclogging.CodeKey, "OK",
+		grpclogging.RecvKey, 1, grpclogging.RecvBytesKey, 4, grpclogging.SentKey, 1)...)
+	server.AssertContains(logfactory.WARN, append(fields, grpclogging.CodeKey, "NotFound", grpclogging.SentKey, 0)...)
+	server.AssertMessage(logfactory.INFO, "checking db")
+	client.AssertContains(logfactory.INFO, append(fields, grpclogging.CodeKey, "OK",
+		grpclogging.SentBytesKey, 4, grpclogging.PeerKey, "bufnet")...)
+	client.AssertContains(logfactory.WARN, append(fields, grpclogging.CodeKey, "NotFound")...)
+
+	e := server.Find(logfactory.INFO, grpclogging.CodeKey, "OK")[0]
+	if _, ok := e.Fields[grpclogging.DurationKey].(time.Duration); !ok {
+		t.Fatalf("expect duration field, but get %+v", e)
+	}
+	if _, ok := e.Fields[grpclogging.PeerKey]; !ok {
+		t.Fatalf("expect peer field, but get %+v", e)
+	}
+}
+
+func TestStreamInterceptors(t *testing.T) {
+	server, client := logtest.New(t), logtest.New(t)
+	hc := grpc_health_v1.NewHealthClient(startServer(t, server, client))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stream, err := hc.Watch(ctx, &grpc_health_v1.HealthCheckRequest{Service: "db"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := stream.Recv(); err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	if _, err := stream.Recv(); status.Code(err) != codes.Canceled {
+		t.Fatalf("expect canceled, but get %v", err)
+	}
+
+	client.AssertContains(logfactory.WARN, grpclogging.MethodKey, "Watch", grpclogging.KindKey, grpclogging.KindStream,
+		grpclogging.CodeKey, "Canceled", grpclogging.SentKey, 1, grpclogging.RecvKey, 1)
+	deadline := time.Now().Add(time.Second)
+	for len(server.Find(logfactory.WARN, grpclogging.MethodKey, "Watch")) == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	server.AssertContains(logfactory.WARN, grpclogging.MethodKey, "Watch", grpclogging.CodeKey, "Canceled",
+		grpclogging.SentKey, 1, grpclogging.RecvKey, 1)
+}
+
+func TestClientStreamInterceptor(t *testing.T) {
+	server, client := logtest.New(t), logtest.New(t)
+	conn := startServer(t, server, client)
+
+	stream, err := conn.NewStream(context.Background(), &sumDesc, "/test.Calculator/Sum")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := int64(1); i <= 3; i++ {
+		if err := stream.SendMsg(wrapperspb.Int64(i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := stream.CloseSend(); err != nil {
+		t.Fatal(err)
+	}
+	sum := &wrapperspb.Int64Value{}
+	if err := stream.RecvMsg(sum); err != nil || sum.Value != 6 {
+		t.Fatalf("expect sum 6, but get %v %v", sum, err)
+	}
+	// 接收到响应后调用即结束，无需再次调用RecvMsg
+	client.AssertContains(logfactory.INFO, grpclogging.ServiceKey, "test.Calculator", grpclogging.MethodKey, "Sum",
+		grpclogging.CodeKey, "OK", grpclogging.SentKey, 3, grpclogging.RecvKey, 1)
+
+	// 未调用RecvMsg时ctx取消也结束调用
+	ctx, cancel := context.WithCancel(context.Background())
+	if _, err := conn.NewStream(ctx, &sumDesc, "/test.Calculator/Sum"); err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	deadline := time.Now().Add(time.Second)
+	for len(client.Find(logfactory.WARN, grpclogging.MethodKey, "Sum")) == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	client.AssertContains(logfactory.WARN, grpclogging.MethodKey, "Sum", grpclogging.CodeKey, "Canceled")
+	if n := len(client.Find(logfactory.INFO, grpclogging.MethodKey, "Sum")); n != 1 {
+		t.Fatalf("expect call finished once, but get %d", n)
+	}
+}
@@ -0,0 +1,231 @@
+/*
+ * Copyright (c) 2022, AcmeStack
+ * All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package httplog
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/acmestack/log4go/logfactory"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// 访问日志的字段
+const (
+	MethodKey     = "method"
+	PathKey       = "path"
+	URIKey        = "uri"
+	ProtoKey      = "proto"
+	StatusKey     = "status"
+	BytesKey      = "bytes"
+	LatencyKey    = "latency"
+	RemoteAddrKey = "remote_addr"
+	RequestIDKey  = "request_id"
+	UserKey       = "user"
+	RefererKey    = "referer"
+	UserAgentKey  = "user_agent"
+)
+
+// DefaultRequestIDHeader 默认的请求ID请求头
+const DefaultRequestIDHeader = "X-Request-ID"
+
+// AccessConfig 访问日志中间件的配置
+type AccessConfig struct {
+	// Logger 输出访问日志的Logger，为nil时使用logfactory.GetLogger("httplog")
+	Logger logfactory.Logger
+	// LevelFunc 根据响应状态码获得日志级别，为nil时使用LevelByStatus
+	LevelFunc func(status int) logfactory.Level
+	// Skip 返回true的请求不输出访问日志（仍会注入请求的Logger），如：ExcludePaths("/health")
+	Skip func(r *http.Request) bool
+	// RequestIDHeader 请求ID的请求头，为空时使用DefaultRequestIDHeader，请求没有该请求头时生成请求ID并写入响应头
+	RequestIDHeader string
+	// GenerateID 生成请求ID的函数，为nil时生成16字节的随机十六进制字符串
+	GenerateID func() string
+}
+
+// LevelByStatus 默认的日志级别：5xx为ERROR，4xx为WARN，其他为INFO
+func LevelByStatus(status int) logfactory.Level {
+	switch {
+	case status >= 500:
+		return logfactory.ERROR
+	case status >= 400:
+		return logfactory.WARN
+	default:
+		return logfactory.INFO
+	}
+}
+
+// ExcludePaths 获得排除路径的Skip函数，以"*"结尾的路径按前缀匹配，如：/static/*
+func ExcludePaths(paths ...string) func(r *http.Request) bool {
+	return func(r *http.Request) bool {
+		for _, p := range paths {
+			if strings.HasSuffix(p, "*") {
+				if strings.HasPrefix(r.URL.Path, p[:len(p)-1]) {
+					return true
+				}
+			} else if r.URL.Path == p {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+type loggerCtxKey struct{}
+
+// NewContext 返回携带logger的ctx
+func NewContext(ctx context.Context, logger logfactory.Logger) context.Context {
+	return context.WithValue(ctx, loggerCtxKey{}, logger)
+}
+
+// FromContext 获得ctx携带的Logger（AccessLog注入的请求Logger附加了请求ID），没有时返回logfactory.GetLogger()
+func FromContext(ctx context.Context) logfactory.Logger {
+	if logger, ok := ctx.Value(loggerCtxKey{}).(logfactory.Logger); ok {
+		return logger
+	}
+	return logfactory.GetLogger().WithContext(ctx)
+}
+
+// AccessLog 输出访问日志的中间件，日志内容为"方法 路径 状态码"，附加请求的各项字段，
+// 并向请求ctx注入附加了请求ID的Logger，可通过FromContext获得
+func AccessLog(config AccessConfig) func(http.Handler) http.Handler {
+	if config.Logger == nil {
+		config.Logger = logfactory.GetLogger("httplog")
+	}
+	if config.LevelFunc == nil {
+		config.LevelFunc = LevelByStatus
+	}
+	if config.RequestIDHeader == "" {
+		config.RequestIDHeader = DefaultRequestIDHeader
+	}
+	if config.GenerateID == nil {
+		config.GenerateID = generateID
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			id := r.Header.Get(config.RequestIDHeader)
+			if id == "" {
+				id = config.GenerateID()
+				w.Header().Set(config.RequestIDHeader, id)
+			}
+			logger := config.Logger.WithContext(r.Context()).WithFields(RequestIDKey, id)
+			r = r.WithContext(NewContext(r.Context(), logger))
+
+			rw := &responseWriter{ResponseWriter: w}
+			next.ServeHTTP(rw, r)
+
+			if config.Skip != nil && config.Skip(r) {
+				return
+			}
+			status := rw.status
+			if status == 0 {
+				status = http.StatusOK
+			}
+			user, _, _ := r.BasicAuth()
+			logger = logger.WithFields(
+				MethodKey, r.Method,
+				PathKey, r.URL.Path,
+				URIKey, r.RequestURI,
+				ProtoKey, r.Proto,
+				StatusKey, status,
+				BytesKey, rw.bytes,
+				LatencyKey, time.Since(start),
+				RemoteAddrKey, r.RemoteAddr,
+				UserKey, user,
+				RefererKey, r.Referer(),
+				UserAgentKey, r.UserAgent())
+			logAt(logger, config.LevelFunc(status), r.Method, r.URL.Path, status)
+		})
+	}
+}
+
+func logAt(logger logfactory.Logger, level logfactory.Level, args ...interface{}) {
+	switch level {
+	case logfactory.DEBUG:
+		logger.DebugLn(args...)
+	case logfactory.INFO:
+		logger.InfoLn(args...)
+	case logfactory.WARN:
+		logger.WarnLn(args...)
+	default:
+		// 访问日志不应触发panic或退出，PANIC、FATAL按ERROR输出
+		logger.ErrorLn(args...)
+	}
+}
+
+func generateID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
+
+// responseWriter 记录响应状态码及写入字节数
+type responseWriter struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (w *responseWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *responseWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += int64(n)
+	return n, err
+}
+
+func (w *responseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		if w.status == 0 {
+			w.status = http.StatusOK
+		}
+		f.Flush()
+	}
+}
+
+func (w *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if h, ok := w.ResponseWriter.(http.Hijacker); ok {
+		if w.status == 0 {
+			w.status = http.StatusSwitchingProtocols
+		}
+		return h.Hijack()
+	}
+	return nil, nil, errors.New("http.Hijacker not implemented")
+}
+
+// Unwrap 供http.ResponseController获得原ResponseWriter
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
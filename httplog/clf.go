@@ -0,0 +1,84 @@
+/*
+ * Copyright (c) 2022, AcmeStack
+ * All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package httplog
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/acmestack/log4go/logfactory"
+	"github.com/acmestack/log4go/util"
+	"io"
+	"net"
+	"strings"
+	"time"
+)
+
+// CLFTimeFormat Common Log Format的时间格式
+const CLFTimeFormat = "02/Jan/2006:15:04:05 -0700"
+
+// CLFFormatter 将AccessLog输出的访问日志格式化为Apache Common Log Format，
+// Combined为true时为Combined Log Format（附加Referer及User-Agent）。
+// 应配置给专用于访问日志的Logging，非访问日志（没有MethodKey字段）只输出日志内容
+type CLFFormatter struct {
+	Combined bool
+}
+
+func (f *CLFFormatter) Format(writer io.Writer, keyValues util.KeyValues) error {
+	if _, ok := keyValues.Get(MethodKey).(string); !ok {
+		_, err := io.WriteString(writer, fmt.Sprintln(strings.TrimSuffix(clfString(keyValues.Get(logfactory.ContentKey)), "\n")))
+		return err
+	}
+
+	buf := bytes.Buffer{}
+	host := clfString(keyValues.Get(RemoteAddrKey))
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	t, ok := keyValues.Get(logfactory.TimestampKey).(time.Time)
+	if !ok {
+		t = time.Now()
+	}
+	size := "-"
+	if n, ok := keyValues.Get(BytesKey).(int64); ok && n > 0 {
+		size = fmt.Sprint(n)
+	}
+	_, _ = fmt.Fprintf(&buf, "%s - %s [%s] \"%s %s %s\" %v %s",
+		clfField(host), clfField(clfString(keyValues.Get(UserKey))), t.Format(CLFTimeFormat),
+		keyValues.Get(MethodKey), clfString(keyValues.Get(URIKey)), clfString(keyValues.Get(ProtoKey)),
+		keyValues.Get(StatusKey), size)
+	if f.Combined {
+		_, _ = fmt.Fprintf(&buf, " %q %q",
+			clfField(clfString(keyValues.Get(RefererKey))), clfField(clfString(keyValues.Get(UserAgentKey))))
+	}
+	buf.WriteByte('\n')
+	_, err := writer.Write(buf.Bytes())
+	return err
+}
+
+func clfString(v interface{}) string {
+	s, _ := v.(string)
+	return s
+}
+
+// clfField 空值输出为"-"
+func clfField(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,52 @@
+/*
+ * Copyright (c) 2022, AcmeStack
+ * All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package httplog 提供HTTP服务的日志中间件
+package httplog
+
+import (
+	"github.com/acmestack/log4go/logfactory"
+	"net/http"
+)
+
+// DefaultForceLevelHeader 默认的强制日志级别请求头
+const DefaultForceLevelHeader = "X-Log-Level"
+
+// ForceLevel 根据请求头标记请求ctx的强制日志级别（logfactory.WithForceLevel），
+// 请求的处理中通过logger.WithContext(r.Context())获得的Logger会输出不低于该级别的日志
+// Param: header - 请求头名称，为空时使用DefaultForceLevelHeader，请求头的值为级别名称，如：debug，
+// allow - 判断请求是否允许强制日志级别，如校验来源或令牌，为nil时拒绝所有请求（避免任意客户端开启DEBUG日志）
+func ForceLevel(header string, allow func(r *http.Request) bool) func(http.Handler) http.Handler {
+	if header == "" {
+		header = DefaultForceLevelHeader
+	}
+	if allow == nil {
+		allow = func(r *http.Request) bool {
+			return false
+		}
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if v := r.Header.Get(header); v != "" && allow(r) {
+				if level, err := logfactory.ParseLevel(v); err == nil {
+					r = r.WithContext(logfactory.WithForceLevel(r.Context(), level))
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
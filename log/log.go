@@ -15,134 +15,252 @@
  * limitations under the License.
  */
 
+// Package log 通过全局默认LoggerFactory（logfactory.GetFactory）输出日志，
+// 重新配置全局LoggerFactory或Logging（logfactory.ResetFactory、logfactory.ResetLogging、GetFactory().Reset）后立即生效，线程安全
 package log
 
 import (
+	"context"
 	"github.com/acmestack/log4go/logfactory"
-	"github.com/acmestack/log4go/util"
+	"sync/atomic"
 )
 
-var (
-	Depth util.Value = util.NewSimpleValue(int(1))
+// rootLogger 缓存的全局Logger，全局LoggerFactory或Logging重置（logfactory.Generation变化）后重新获取
+type rootLogger struct {
+	generation uint64
+	logger     logfactory.Logger
+}
 
-	Fields util.Value = util.NewSimpleValue(nil)
-)
+var root atomic.Value
 
-var defaultLogging util.Value = util.NewSimpleValue(logfactory.NewLogging())
+// std 获得包函数使用的Logger，调用深度为包函数的调用者
+func std() logfactory.Logger {
+	gen := logfactory.Generation()
+	if r, ok := root.Load().(*rootLogger); ok && r.generation == gen {
+		return r.logger
+	}
+	r := &rootLogger{
+		generation: gen,
+		// 包函数 <- 调用者
+		logger: logfactory.GetLogger().WithDepth(1),
+	}
+	root.Store(r)
+	return r.logger
+}
 
-// DefaultLogging 获得默认Logging
+// DefaultLogging 获得全局默认LoggerFactory的Logging
 func DefaultLogging() logfactory.Logging {
-	return defaultLogging.Load().(logfactory.Logging)
+	return logfactory.GetFactory().GetLogging()
 }
 
+// NewLogging 使用opts创建Logging并重置为全局默认Logging（logfactory.ResetLogging）
 func NewLogging(opts ...logfactory.LoggingOpt) {
-	defaultLogging.Store(logfactory.NewLogging(opts...))
+	logfactory.ResetLogging(logfactory.NewLogging(opts...))
+}
+
+// SetLevel 设置全局默认Logging的日志严重级别
+func SetLevel(level logfactory.Level) {
+	DefaultLogging().SetLogLevel(level)
+}
+
+// GetLogger 通过全局默认LoggerFactory获取Logger，同logfactory.GetLogger
+func GetLogger(o ...interface{}) logfactory.Logger {
+	return logfactory.GetLogger(o...)
+}
+
+// WithName 获得附加名称的Logger
+func WithName(name string) logfactory.Logger {
+	return logfactory.GetLogger().WithName(name)
+}
+
+// WithFields 获得附加信息的Logger
+func WithFields(keyAndValues ...interface{}) logfactory.Logger {
+	return logfactory.GetLogger().WithFields(keyAndValues...)
+}
+
+// WithDepth 获得调整了调用深度的Logger
+func WithDepth(depth int) logfactory.Logger {
+	return logfactory.GetLogger().WithDepth(depth)
+}
+
+// WithContext 获得附加ctx中日志信息的Logger
+func WithContext(ctx context.Context) logfactory.Logger {
+	return logfactory.GetLogger().WithContext(ctx)
+}
+
+// WithStack 获得输出日志时附加当前协程堆栈的Logger
+func WithStack() logfactory.Logger {
+	return logfactory.GetLogger().WithStack()
+}
+
+// WithGroup 获得分组的Logger，之后附加信息的键添加分组前缀
+func WithGroup(name string) logfactory.Logger {
+	return logfactory.GetLogger().WithGroup(name)
+}
+
+// RecoverAndLog 捕获panic并输出ERROR级别日志，需直接defer调用：defer log.RecoverAndLog()
+func RecoverAndLog() {
+	if v := recover(); v != nil {
+		// 与Go等包函数一致，通过std()获得的Logger输出
+		if r, ok := std().(logfactory.RecoveredLogger); ok {
+			r.LogRecovered(v)
+		} else {
+			logfactory.LogRecovered(DefaultLogging(), nil, v)
+		}
+	}
+}
+
+// Go 在新的协程中执行f，f发生panic时输出ERROR级别日志
+func Go(f func()) {
+	std().Go(f)
 }
 
-// Debug 使用默认的Logging，输出Debug级别的日志
+// DebugEnabled 判断全局默认Logging是否输出Debug级别的日志
+func DebugEnabled() bool {
+	return std().DebugEnabled()
+}
+
+// Debug 使用全局默认Logger，输出Debug级别的日志
 func Debug(args ...interface{}) {
-	DefaultLogging().Log(logfactory.DEBUG, Depth.Load().(int), getLogField(), args...)
+	std().Debug(args...)
 }
 
-// Debugln 使用默认的Logging，输出Debug级别的日志
+// DebugLn 使用全局默认Logger，输出Debug级别的日志
 func DebugLn(args ...interface{}) {
-	DefaultLogging().LogLn(logfactory.DEBUG, Depth.Load().(int), getLogField(), args...)
+	std().DebugLn(args...)
 }
 
-// Debugf 使用默认的Logging，输出Debug级别的日志
+// DebugF 使用全局默认Logger，输出Debug级别的日志
 func DebugF(fmt string, args ...interface{}) {
-	DefaultLogging().LogF(logfactory.DEBUG, Depth.Load().(int), getLogField(), fmt, args...)
+	std().DebugF(fmt, args...)
+}
+
+// DebugT 使用全局默认Logger，输出Debug级别的日志（消息模板，见logfactory.Logging.LogT）
+func DebugT(template string, args ...interface{}) {
+	std().DebugT(template, args...)
 }
 
-// Info 使用默认的Logging，输出Info级别的日志
+// InfoEnabled 判断全局默认Logging是否输出Info级别的日志
+func InfoEnabled() bool {
+	return std().InfoEnabled()
+}
+
+// Info 使用全局默认Logger，输出Info级别的日志
 func Info(args ...interface{}) {
-	DefaultLogging().Log(logfactory.INFO, Depth.Load().(int), getLogField(), args...)
+	std().Info(args...)
 }
 
-// 使用默认的Logging，输出Info级别的日志
+// InfoLn 使用全局默认Logger，输出Info级别的日志
 func InfoLn(args ...interface{}) {
-	DefaultLogging().LogLn(logfactory.INFO, Depth.Load().(int), getLogField(), args...)
+	std().InfoLn(args...)
 }
 
-// Infof 使用默认的Logging，输出Info级别的日志
+// InfoF 使用全局默认Logger，输出Info级别的日志
 func InfoF(fmt string, args ...interface{}) {
-	DefaultLogging().LogF(logfactory.INFO, Depth.Load().(int), getLogField(), fmt, args...)
+	std().InfoF(fmt, args...)
 }
 
-// 使用默认的Logging，输出Warn级别的日志
+// InfoT 使用全局默认Logger，输出Info级别的日志（消息模板，见logfactory.Logging.LogT）
+func InfoT(template string, args ...interface{}) {
+	std().InfoT(template, args...)
+}
+
+// WarnEnabled 判断全局默认Logging是否输出Warn级别的日志
+func WarnEnabled() bool {
+	return std().WarnEnabled()
+}
+
+// Warn 使用全局默认Logger，输出Warn级别的日志
 func Warn(args ...interface{}) {
-	DefaultLogging().Log(logfactory.WARN, Depth.Load().(int), getLogField(), args...)
+	std().Warn(args...)
 }
 
-// 使用默认的Logging，输出Warn级别的日志
+// WarnLn 使用全局默认Logger，输出Warn级别的日志
 func WarnLn(args ...interface{}) {
-	DefaultLogging().LogLn(logfactory.WARN, Depth.Load().(int), getLogField(), args...)
+	std().WarnLn(args...)
 }
 
-// 使用默认的Logging，输出Warn级别的日志
+// WarnF 使用全局默认Logger，输出Warn级别的日志
 func WarnF(fmt string, args ...interface{}) {
-	DefaultLogging().LogF(logfactory.WARN, Depth.Load().(int), getLogField(), fmt, args...)
+	std().WarnF(fmt, args...)
 }
 
-// 使用默认的Logging，输出Error级别的日志
+// WarnT 使用全局默认Logger，输出Warn级别的日志（消息模板，见logfactory.Logging.LogT）
+func WarnT(template string, args ...interface{}) {
+	std().WarnT(template, args...)
+}
+
+// ErrorEnabled 判断全局默认Logging是否输出Error级别的日志
+func ErrorEnabled() bool {
+	return std().ErrorEnabled()
+}
+
+// Error 使用全局默认Logger，输出Error级别的日志
 func Error(args ...interface{}) {
-	DefaultLogging().Log(logfactory.ERROR, Depth.Load().(int), getLogField(), args...)
+	std().Error(args...)
 }
 
-// 使用默认的Logging，输出Error级别的日志
+// ErrorLn 使用全局默认Logger，输出Error级别的日志
 func ErrorLn(args ...interface{}) {
-	DefaultLogging().LogLn(logfactory.ERROR, Depth.Load().(int), getLogField(), args...)
+	std().ErrorLn(args...)
 }
 
-// 使用默认的Logging，输出Error级别的日志
+// ErrorF 使用全局默认Logger，输出Error级别的日志
 func ErrorF(fmt string, args ...interface{}) {
-	DefaultLogging().LogF(logfactory.ERROR, Depth.Load().(int), getLogField(), fmt, args...)
+	std().ErrorF(fmt, args...)
+}
+
+// ErrorT 使用全局默认Logger，输出Error级别的日志（消息模板，见logfactory.Logging.LogT）
+func ErrorT(template string, args ...interface{}) {
+	std().ErrorT(template, args...)
 }
 
-// 使用默认的Logging，输出Panic级别的日志，注意会触发panic
+// PanicEnabled 判断全局默认Logging是否输出Panic级别的日志
+func PanicEnabled() bool {
+	return std().PanicEnabled()
+}
+
+// Panic 使用全局默认Logger，输出Panic级别的日志，注意会触发panic
 func Panic(args ...interface{}) {
-	DefaultLogging().Log(logfactory.PANIC, Depth.Load().(int), getLogField(), args...)
+	std().Panic(args...)
 }
 
-// 使用默认的Logging，输出Panic级别的日志，注意会触发panic
+// PanicLn 使用全局默认Logger，输出Panic级别的日志，注意会触发panic
 func PanicLn(args ...interface{}) {
-	DefaultLogging().LogLn(logfactory.PANIC, Depth.Load().(int), getLogField(), args...)
+	std().PanicLn(args...)
 }
 
-// 使用默认的Logging，输出Panic级别的日志，注意会触发panic
+// PanicF 使用全局默认Logger，输出Panic级别的日志，注意会触发panic
 func PanicF(fmt string, args ...interface{}) {
-	DefaultLogging().LogF(logfactory.PANIC, Depth.Load().(int), getLogField(), fmt, args...)
+	std().PanicF(fmt, args...)
 }
 
-// 使用默认的Logging，输出Fatal级别的日志，注意会触发程序退出
-func Fatal(args ...interface{}) {
-	DefaultLogging().Log(logfactory.FATAL, Depth.Load().(int), getLogField(), args...)
+// PanicT 使用全局默认Logger，输出Panic级别的日志（消息模板，见logfactory.Logging.LogT），注意会触发panic
+func PanicT(template string, args ...interface{}) {
+	std().PanicT(template, args...)
 }
 
-// 使用默认的Logging，输出Fatal级别的日志，注意会触发程序退出
-func FatalLn(args ...interface{}) {
-	DefaultLogging().LogLn(logfactory.FATAL, Depth.Load().(int), getLogField(), args...)
+// FatalEnabled 判断全局默认Logging是否输出Fatal级别的日志
+func FatalEnabled() bool {
+	return std().FatalEnabled()
 }
 
-// 使用默认的Logging，输出Fatal级别的日志，注意会触发程序退出
-func FatalF(fmt string, args ...interface{}) {
-	DefaultLogging().LogF(logfactory.FATAL, Depth.Load().(int), getLogField(), fmt, args...)
+// Fatal 使用全局默认Logger，输出Fatal级别的日志，注意会触发程序退出
+func Fatal(args ...interface{}) {
+	std().Fatal(args...)
 }
 
-func getLogField() util.KeyValues {
-	ret := Fields.Load()
-	if ret == nil {
-		return nil
-	}
-	return ret.(util.KeyValues)
+// FatalLn 使用全局默认Logger，输出Fatal级别的日志，注意会触发程序退出
+func FatalLn(args ...interface{}) {
+	std().FatalLn(args...)
 }
 
-// 配置默认的调用深度
-func WithDepth(depth int) {
-	Depth.Store(depth)
+// FatalF 使用全局默认Logger，输出Fatal级别的日志，注意会触发程序退出
+func FatalF(fmt string, args ...interface{}) {
+	std().FatalF(fmt, args...)
 }
 
-// 配置默认附件信息
-func WithFields(keyAndValues ...interface{}) {
-	Fields.Store(util.NewKeyValues(keyAndValues...))
+// FatalT 使用全局默认Logger，输出Fatal级别的日志（消息模板，见logfactory.Logging.LogT），注意会触发程序退出
+func FatalT(template string, args ...interface{}) {
+	std().FatalT(template, args...)
 }
@@ -0,0 +1,67 @@
+/*
+ * Copyright (c) 2022, AcmeStack
+ * All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package logfactory
+
+import (
+	"context"
+	"github.com/acmestack/log4go/util"
+)
+
+type forceLevelCtxKey struct{}
+
+// WithForceLevel 返回标记了强制日志级别的ctx，通过Logger.WithContext获得的Logger会输出不低于该级别的日志，
+// 不受Logging及Logger名称前缀日志级别的限制，用于临时开启单个请求的DEBUG日志
+func WithForceLevel(ctx context.Context, level Level) context.Context {
+	return context.WithValue(ctx, forceLevelCtxKey{}, level)
+}
+
+// ForceLevelFromContext 获得ctx中标记的强制日志级别
+func ForceLevelFromContext(ctx context.Context) (Level, bool) {
+	if ctx == nil {
+		return DEBUG, false
+	}
+	level, ok := ctx.Value(forceLevelCtxKey{}).(Level)
+	return level, ok
+}
+
+// ForcedLevel 获得附加信息中的强制日志级别（ForceLevelKey），
+// 也可通过logger.WithFields(logfactory.ForceLevelKey, logfactory.DEBUG)标记
+func ForcedLevel(keyValues util.KeyValues) (Level, bool) {
+	if keyValues == nil {
+		return DEBUG, false
+	}
+	level, ok := keyValues.Get(ForceLevelKey).(Level)
+	return level, ok
+}
+
+// ForceEnabled 判断附加信息中的强制日志级别（ForceLevelKey）是否允许输出level级别的日志，用于实现Logger.IsEnabled
+func ForceEnabled(keyValues util.KeyValues, level Level) bool {
+	forced, ok := ForcedLevel(keyValues)
+	return ok && forced >= level
+}
+
+// ContextFields 返回添加了ctx中强制日志级别的附加信息，ctx没有标记时返回原附加信息，用于实现Logger.WithContext
+func ContextFields(ctx context.Context, keyValues util.KeyValues) util.KeyValues {
+	level, ok := ForceLevelFromContext(ctx)
+	if !ok {
+		return keyValues
+	}
+	ret := keyValues.Clone()
+	_ = ret.Add(ForceLevelKey, level)
+	return ret
+}
@@ -18,6 +18,7 @@
 package logfactory
 
 import (
+	"context"
 	"github.com/acmestack/log4go/util"
 )
 
@@ -26,6 +27,7 @@ type defaultlog struct {
 	depth   int
 	fields  util.KeyValues
 	name    string
+	group   string
 }
 
 //// Deprecated: use logfactory.GetLogger instead
@@ -44,7 +46,8 @@ func defaultLogger(loggingValue Logging, fields util.KeyValues, name ...string)
 	var t string
 	if len(name) > 0 {
 		t = name[0]
-		if t != "" {
+		// 名称未变化时不修改，避免复制共享的附加信息
+		if t != "" && fields.Get(NameKey) != t {
 			fields.Add(NameKey, t)
 		}
 	}
@@ -72,6 +75,10 @@ func (l *defaultlog) DebugF(fmt string, args ...interface{}) {
 	l.logging.LogF(DEBUG, l.depth, l.fields, fmt, args...)
 }
 
+func (l *defaultlog) DebugT(template string, args ...interface{}) {
+	l.logging.LogT(DEBUG, l.depth, l.fields, template, args...)
+}
+
 func (l *defaultlog) InfoEnabled() bool {
 	return l.IsEnabled(INFO)
 }
@@ -88,6 +95,10 @@ func (l *defaultlog) InfoF(fmt string, args ...interface{}) {
 	l.logging.LogF(INFO, l.depth, l.fields, fmt, args...)
 }
 
+func (l *defaultlog) InfoT(template string, args ...interface{}) {
+	l.logging.LogT(INFO, l.depth, l.fields, template, args...)
+}
+
 func (l *defaultlog) WarnEnabled() bool {
 	return l.IsEnabled(WARN)
 }
@@ -104,6 +115,10 @@ func (l *defaultlog) WarnF(fmt string, args ...interface{}) {
 	l.logging.LogF(WARN, l.depth, l.fields, fmt, args...)
 }
 
+func (l *defaultlog) WarnT(template string, args ...interface{}) {
+	l.logging.LogT(WARN, l.depth, l.fields, template, args...)
+}
+
 func (l *defaultlog) ErrorEnabled() bool {
 	return l.IsEnabled(ERROR)
 }
@@ -120,6 +135,10 @@ func (l *defaultlog) ErrorF(fmt string, args ...interface{}) {
 	l.logging.LogF(ERROR, l.depth, l.fields, fmt, args...)
 }
 
+func (l *defaultlog) ErrorT(template string, args ...interface{}) {
+	l.logging.LogT(ERROR, l.depth, l.fields, template, args...)
+}
+
 func (l *defaultlog) PanicEnabled() bool {
 	return l.IsEnabled(PANIC)
 }
@@ -136,6 +155,10 @@ func (l *defaultlog) PanicF(fmt string, args ...interface{}) {
 	l.logging.LogF(PANIC, l.depth, l.fields, fmt, args...)
 }
 
+func (l *defaultlog) PanicT(template string, args ...interface{}) {
+	l.logging.LogT(PANIC, l.depth, l.fields, template, args...)
+}
+
 func (l *defaultlog) FatalEnabled() bool {
 	return l.IsEnabled(FATAL)
 }
@@ -152,8 +175,32 @@ func (l *defaultlog) FatalF(fmt string, args ...interface{}) {
 	l.logging.LogF(FATAL, l.depth, l.fields, fmt, args...)
 }
 
+func (l *defaultlog) FatalT(template string, args ...interface{}) {
+	l.logging.LogT(FATAL, l.depth, l.fields, template, args...)
+}
+
+func (l *defaultlog) RecoverAndLog() {
+	if v := recover(); v != nil {
+		l.LogRecovered(v)
+	}
+}
+
+func (l *defaultlog) LogRecovered(v interface{}) {
+	LogRecovered(l.logging, l.fields, v)
+}
+
+func (l *defaultlog) Go(f func()) {
+	go func() {
+		defer l.RecoverAndLog()
+		f()
+	}()
+}
+
 func (l *defaultlog) IsEnabled(severityLevel Level) bool {
-	return l.logging.IsEnabled(severityLevel)
+	if ForceEnabled(l.fields, severityLevel) {
+		return true
+	}
+	return l.logging.IsEnabledByName(l.name, severityLevel)
 }
 
 func (l *defaultlog) WithName(name string) Logger {
@@ -167,6 +214,7 @@ func (l *defaultlog) WithName(name string) Logger {
 	ret := defaultLogger(l.logging, l.fields.Clone(), name)
 	ret.fields.Add(NameKey, ret.name)
 	ret.depth = l.depth
+	ret.group = l.group
 
 	return ret
 }
@@ -176,8 +224,9 @@ func (l *defaultlog) WithFields(keyAndValues ...interface{}) Logger {
 		return nil
 	}
 	ret := defaultLogger(l.logging, l.fields.Clone(), l.name)
-	ret.fields.Add(keyAndValues...)
+	ret.fields.Add(GroupFields(l.group, keyAndValues...)...)
 	ret.depth = l.depth
+	ret.group = l.group
 
 	return ret
 }
@@ -188,6 +237,48 @@ func (l *defaultlog) WithDepth(depth int) Logger {
 	}
 	ret := defaultLogger(l.logging, l.fields.Clone(), l.name)
 	ret.depth += depth
+	ret.group = l.group
+
+	return ret
+}
+
+func (l *defaultlog) WithStack() Logger {
+	if l == nil {
+		return nil
+	}
+	ret := defaultLogger(l.logging, l.fields.Clone(), l.name)
+	ret.fields.Add(StackKey, StackCurrent)
+	ret.depth = l.depth
+	ret.group = l.group
+
+	return ret
+}
+
+func (l *defaultlog) WithContext(ctx context.Context) Logger {
+	if l == nil {
+		return nil
+	}
+	fields := ContextFields(ctx, l.fields)
+	if fields == l.fields {
+		return l
+	}
+	ret := defaultLogger(l.logging, fields, l.name)
+	ret.depth = l.depth
+	ret.group = l.group
+
+	return ret
+}
+
+func (l *defaultlog) WithGroup(name string) Logger {
+	if l == nil {
+		return nil
+	}
+	if name == "" {
+		return l
+	}
+	ret := defaultLogger(l.logging, l.fields.Clone(), l.name)
+	ret.depth = l.depth
+	ret.group = GroupName(l.group, name)
 
 	return ret
 }
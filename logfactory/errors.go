@@ -0,0 +1,203 @@
+/*
+ * Copyright (c) 2022, AcmeStack
+ * All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package logfactory
+
+import (
+	"errors"
+	"fmt"
+	"github.com/acmestack/log4go/util"
+	"reflect"
+	"strings"
+)
+
+// maxErrorDepth 解析错误链的最大深度，防止错误链中存在环
+const maxErrorDepth = 32
+
+// ErrorInfo 结构化的错误信息，作为日志的ErrorKey字段，
+// JsonFormatter输出为对象，TextFormatter输出为：错误信息 [类型 <- 原因的类型 ...]
+type ErrorInfo struct {
+	// Message error.Error()的值
+	Message string `json:"message"`
+	// Type error的具体类型，如：*fs.PathError
+	Type string `json:"type"`
+	// Causes errors.Unwrap获得的原因，errors.Join等包含多个错误时有多个原因
+	Causes []*ErrorInfo `json:"causes,omitempty"`
+	// Stack error自带的堆栈（如github.com/pkg/errors创建的error）
+	Stack Stack `json:"stack,omitempty"`
+
+	err error
+}
+
+// Err 获得err的结构化错误信息，作为日志参数时，会以ErrorKey字段输出，日志内容中为err.Error()，
+// 如：logger.Error("save failed: ", logfactory.Err(err))
+// err为nil时返回nil
+func Err(err error) *ErrorInfo {
+	return newErrorInfo(err, 0)
+}
+
+func newErrorInfo(err error, depth int) *ErrorInfo {
+	if err == nil {
+		return nil
+	}
+	ret := &ErrorInfo{
+		Message: err.Error(),
+		Type:    reflect.TypeOf(err).String(),
+		Stack:   errorStack(err),
+		err:     err,
+	}
+	if depth >= maxErrorDepth {
+		return ret
+	}
+	switch e := err.(type) {
+	case interface{ Unwrap() error }:
+		if cause := newErrorInfo(e.Unwrap(), depth+1); cause != nil {
+			ret.Causes = []*ErrorInfo{cause}
+		}
+	case interface{ Unwrap() []error }:
+		for _, v := range e.Unwrap() {
+			if cause := newErrorInfo(v, depth+1); cause != nil {
+				ret.Causes = append(ret.Causes, cause)
+			}
+		}
+	}
+	return ret
+}
+
+// Unwrap 返回原始的error
+func (e *ErrorInfo) Unwrap() error {
+	return e.err
+}
+
+// StackTrace 返回错误链中（深度优先）第一个携带的堆栈，没有时返回nil
+func (e *ErrorInfo) StackTrace() Stack {
+	if e == nil {
+		return nil
+	}
+	if len(e.Stack) > 0 {
+		return e.Stack
+	}
+	for _, c := range e.Causes {
+		if s := c.StackTrace(); s != nil {
+			return s
+		}
+	}
+	return nil
+}
+
+func (e *ErrorInfo) String() string {
+	if e == nil {
+		return ""
+	}
+	buf := strings.Builder{}
+	buf.WriteString(e.Message)
+	buf.WriteString(" [")
+	e.writeTypes(&buf)
+	buf.WriteByte(']')
+	return buf.String()
+}
+
+func (e *ErrorInfo) writeTypes(buf *strings.Builder) {
+	buf.WriteString(e.Type)
+	switch len(e.Causes) {
+	case 0:
+	case 1:
+		buf.WriteString(" <- ")
+		e.Causes[0].writeTypes(buf)
+	default:
+		buf.WriteString(" <- (")
+		for i, c := range e.Causes {
+			if i > 0 {
+				buf.WriteString(", ")
+			}
+			c.writeTypes(buf)
+		}
+		buf.WriteByte(')')
+	}
+}
+
+// errorStack 获得error自带的堆栈，支持：
+// Callers() []uintptr（如github.com/go-errors/errors），
+// StackTrace() 返回元素为uintptr类型的切片（如github.com/pkg/errors）
+func errorStack(err error) Stack {
+	if e, ok := err.(interface{ Callers() []uintptr }); ok {
+		return callersStack(e.Callers(), nil)
+	}
+	m := reflect.ValueOf(err).MethodByName("StackTrace")
+	if !m.IsValid() || m.Type().NumIn() != 0 || m.Type().NumOut() != 1 {
+		return nil
+	}
+	out := m.Type().Out(0)
+	if out.Kind() != reflect.Slice || out.Elem().Kind() != reflect.Uintptr {
+		return nil
+	}
+	v := m.Call(nil)[0]
+	pc := make([]uintptr, v.Len())
+	for i := range pc {
+		pc[i] = uintptr(v.Index(i).Uint())
+	}
+	return callersStack(pc, nil)
+}
+
+// extractErrors 返回第一个*ErrorInfo，并将args中的*ErrorInfo替换为错误信息，
+// unwrap为true时替换为原始的error，使格式化时%w等动词可以正确处理
+func extractErrors(args []interface{}, unwrap bool) ([]interface{}, *ErrorInfo) {
+	var ret *ErrorInfo
+	for i, v := range args {
+		e, ok := v.(*ErrorInfo)
+		if !ok || e == nil {
+			continue
+		}
+		if ret == nil {
+			ret = e
+			args = append([]interface{}{}, args...)
+		}
+		if unwrap {
+			args[i] = e.err
+		} else {
+			args[i] = e.Message
+		}
+	}
+	return args, ret
+}
+
+// wrappedError 使用fmt.Errorf格式化包含%w的日志，返回日志内容及被包装的error
+func wrappedError(format string, args ...interface{}) (string, *ErrorInfo) {
+	err := fmt.Errorf(format, args...)
+	if w := errors.Unwrap(err); w != nil {
+		return err.Error(), Err(w)
+	}
+	if _, ok := err.(interface{ Unwrap() []error }); ok {
+		return err.Error(), Err(err)
+	}
+	return err.Error(), nil
+}
+
+// withError 返回添加了ErrorKey字段的附加信息，不修改原附加信息
+func withError(keyValues util.KeyValues, e *ErrorInfo) util.KeyValues {
+	if e == nil {
+		return keyValues
+	}
+	var ret util.KeyValues
+	if keyValues != nil {
+		ret = keyValues.Clone()
+	} else {
+		ret = util.NewKeyValues()
+	}
+	_ = ret.Add(ErrorKey, e)
+	return ret
+}
@@ -0,0 +1,195 @@
+/*
+ * Copyright (c) 2022, AcmeStack
+ * All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package logfactory
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"runtime"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// FatalMode Fatal级别日志的处理方式
+type FatalMode int
+
+const (
+	// FatalExit 执行关闭钩子、写入Writer缓存后调用退出处理函数（默认）
+	FatalExit FatalMode = iota
+	// FatalAsError 仅输出日志并写入Writer缓存，不执行关闭钩子也不退出，适用于不应结束宿主程序的库
+	FatalAsError
+)
+
+// 默认值
+var (
+	DefaultExitCode        = 1
+	DefaultShutdownTimeout = 5 * time.Second
+)
+
+// ShutdownHook 程序因Fatal退出前执行的关闭钩子，超时由ctx控制
+type ShutdownHook func(ctx context.Context) error
+
+type shutdownHook struct {
+	name string
+	hook ShutdownHook
+}
+
+// fatalShutdown 一次Fatal触发的关闭过程，done在钩子执行完毕后关闭
+type fatalShutdown struct {
+	once sync.Once
+	done chan struct{}
+}
+
+var (
+	hookLock sync.Mutex
+	hooks    []*shutdownHook
+	// shutdown 正在进行的关闭过程，钩子执行完毕后置为nil
+	shutdown *fatalShutdown
+	// hookGoroutines 正在执行关闭钩子的协程ID
+	hookGoroutines sync.Map
+)
+
+// AddShutdownHook 注册关闭钩子，Fatal退出前按注册顺序执行（线程安全）
+// Param: name - 钩子名称，用于错误信息，hook - 关闭钩子
+// Return: 注销该钩子的函数
+func AddShutdownHook(name string, hook ShutdownHook) (remove func()) {
+	h := &shutdownHook{name: name, hook: hook}
+	hookLock.Lock()
+	hooks = append(hooks, h)
+	hookLock.Unlock()
+
+	return func() {
+		hookLock.Lock()
+		defer hookLock.Unlock()
+		for i, v := range hooks {
+			if v == h {
+				hooks = append(hooks[:i:i], hooks[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// RunShutdownHooks 按注册顺序执行所有关闭钩子，单个钩子失败或超时不影响后续钩子的执行，
+// 所有钩子共用ctx的超时，返回第一个错误。也可在程序正常退出时调用。
+// 钩子超时后不会被终止，执行钩子的协程会继续运行直到钩子返回（程序退出时随之结束）
+func RunShutdownHooks(ctx context.Context) error {
+	return runShutdownHooks(ctx, nil)
+}
+
+func runShutdownHooks(ctx context.Context, onError func(err error)) error {
+	hookLock.Lock()
+	hs := append([]*shutdownHook(nil), hooks...)
+	hookLock.Unlock()
+
+	var ret error
+	for _, h := range hs {
+		hook := h.hook
+		err := runWithContext(ctx, func() error {
+			id := goroutineID()
+			hookGoroutines.Store(id, struct{}{})
+			defer hookGoroutines.Delete(id)
+			return hook(ctx)
+		})
+		if err != nil {
+			err = fmt.Errorf("shutdown hook %s failed: %w", h.name, err)
+			if ret == nil {
+				ret = err
+			}
+			if onError != nil {
+				onError(err)
+			}
+		}
+	}
+	return ret
+}
+
+// processFatal 处理Fatal级别日志：执行关闭钩子、写入Writer缓存，然后以配置的退出码退出。
+// 多个协程同时输出Fatal日志时，钩子只执行一次，其他协程等待钩子执行完毕后再退出；
+// 关闭钩子所在协程中再次输出Fatal日志时不重复执行也不等待钩子，直接退出
+func (l *logging) processFatal() {
+	if l.fatalMode == FatalAsError {
+		l.flushBeforeExit()
+		return
+	}
+	if _, ok := hookGoroutines.Load(goroutineID()); !ok {
+		hookLock.Lock()
+		s := shutdown
+		if s == nil {
+			s = &fatalShutdown{done: make(chan struct{})}
+			shutdown = s
+		}
+		hookLock.Unlock()
+
+		s.once.Do(func() {
+			ctx, cancel := context.WithTimeout(context.Background(), l.shutdownTimeout)
+			_ = runShutdownHooks(ctx, func(err error) {
+				l.handleError(FATAL, err)
+			})
+			cancel()
+			hookLock.Lock()
+			shutdown = nil
+			hookLock.Unlock()
+			close(s.done)
+		})
+		<-s.done
+	}
+	l.flushBeforeExit()
+	l.exitFunc(l.exitCode)
+}
+
+// goroutineID 获得当前协程的ID
+func goroutineID() uint64 {
+	var buf [64]byte
+	b := buf[:runtime.Stack(buf[:], false)]
+	// 格式为：goroutine 18 [running]:
+	b = bytes.TrimPrefix(b, []byte("goroutine "))
+	if i := bytes.IndexByte(b, ' '); i >= 0 {
+		b = b[:i]
+	}
+	id, _ := strconv.ParseUint(string(b), 10, 64)
+	return id
+}
+
+func defaultExit(code int) {
+	os.Exit(code)
+}
+
+// SetExitCode 配置内置Logging Fatal退出时的退出码，默认为DefaultExitCode
+func SetExitCode(code int) func(*logging) {
+	return func(logging *logging) {
+		logging.exitCode = code
+	}
+}
+
+// SetFatalMode 配置内置Logging Fatal级别日志的处理方式，默认为FatalExit
+func SetFatalMode(mode FatalMode) func(*logging) {
+	return func(logging *logging) {
+		logging.fatalMode = mode
+	}
+}
+
+// SetShutdownTimeout 配置内置Logging Fatal退出前执行所有关闭钩子的超时时间，默认为DefaultShutdownTimeout
+func SetShutdownTimeout(timeout time.Duration) func(*logging) {
+	return func(logging *logging) {
+		logging.shutdownTimeout = timeout
+	}
+}
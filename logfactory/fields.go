@@ -0,0 +1,122 @@
+/*
+ * Copyright (c) 2022, AcmeStack
+ * All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package logfactory
+
+import (
+	"fmt"
+	"github.com/acmestack/log4go/util"
+)
+
+// KeyCollision 附加信息的键与内置键（TimestampKey、LevelKey、CallerKey、ContentKey）冲突时的处理策略，
+// 仅在配置了Formatter或RecordWriter时生效，默认的文本格式不输出附加信息的键
+type KeyCollision = util.KeyCollision
+
+const (
+	// CollisionRename 在冲突的键后添加后缀（默认DefaultCollisionSuffix），默认策略
+	CollisionRename = util.CollisionRename
+	// CollisionPrefix 在所有附加信息的键前添加前缀（默认DefaultCollisionPrefix），Logger内部使用的键除外
+	CollisionPrefix = util.CollisionPrefix
+	// CollisionReject 丢弃冲突的附加信息，并以ErrKeyCollision调用ErrorHandler
+	CollisionReject = util.CollisionReject
+	// CollisionOverwrite 附加信息覆盖内置键的值（旧版本行为）
+	CollisionOverwrite = util.CollisionOverwrite
+)
+
+const (
+	// DefaultCollisionSuffix CollisionRename默认的后缀
+	DefaultCollisionSuffix = util.DefaultCollisionSuffix
+	// DefaultCollisionPrefix CollisionPrefix默认的前缀
+	DefaultCollisionPrefix = util.DefaultCollisionPrefix
+)
+
+// ErrKeyCollision CollisionReject策略丢弃附加信息时的错误
+var ErrKeyCollision = util.ErrKeyCollision
+
+// SetKeyCollision 配置内置Logging实现的附加信息键冲突处理策略，affix为CollisionPrefix的前缀或CollisionRename的后缀，
+// 为空时使用默认值。默认为CollisionRename，需要旧版本的覆盖行为时配置为CollisionOverwrite
+func SetKeyCollision(policy KeyCollision, affix string) func(*logging) {
+	return func(logging *logging) {
+		logging.keyCollision = policy
+		logging.collisionAffix = affix
+	}
+}
+
+// isBuiltinKey 判断是否为输出时由Logging添加的键
+func isBuiltinKey(key string) bool {
+	switch key {
+	case TimestampKey, LevelKey, CallerKey, ContentKey:
+		return true
+	}
+	return false
+}
+
+// fieldKey 获得附加信息输出时的键，返回false时丢弃该附加信息
+func (l *logging) fieldKey(level Level, key string, keyValues util.KeyValues) (string, bool) {
+	if isInternalKey(key) || (!isBuiltinKey(key) && l.keyCollision != CollisionPrefix) {
+		return key, true
+	}
+	ret, ok := l.keyCollision.ResolveKey(key, l.collisionAffix, func(k string) bool {
+		return isBuiltinKey(k) || hasKey(keyValues, k)
+	})
+	if !ok {
+		l.handleError(level, fmt.Errorf("%w: %s", ErrKeyCollision, key))
+	}
+	return ret, ok
+}
+
+func hasKey(keyValues util.KeyValues, key string) bool {
+	found := false
+	keyValues.Range(func(k string, _ interface{}) bool {
+		found = k == key
+		return !found
+	})
+	return found
+}
+
+// isInternalKey 判断是否为Logger内部使用的附加信息键，这些键不添加分组前缀
+func isInternalKey(key string) bool {
+	switch key {
+	case NameKey, PanicKey, StackKey, ErrorKey, ForceLevelKey, TemplateKey:
+		return true
+	}
+	return false
+}
+
+// GroupName 获得嵌套分组的名称，以util.GroupSeparator连接，用于实现Logger.WithGroup
+func GroupName(parent, name string) string {
+	if parent == "" {
+		return name
+	}
+	return parent + util.GroupSeparator + name
+}
+
+// GroupFields 为keyAndValues中的键添加分组前缀（group + util.GroupSeparator），group为空时返回原参数，
+// Logger内部使用的键（如ForceLevelKey、StackKey）不添加前缀，用于实现Logger.WithGroup
+func GroupFields(group string, keyAndValues ...interface{}) []interface{} {
+	if group == "" {
+		return keyAndValues
+	}
+	ret := make([]interface{}, len(keyAndValues))
+	copy(ret, keyAndValues)
+	for i := 0; i < len(ret); i += 2 {
+		if k, ok := ret[i].(string); ok && !isInternalKey(k) {
+			ret[i] = group + util.GroupSeparator + k
+		}
+	}
+	return ret
+}
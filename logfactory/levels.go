@@ -0,0 +1,126 @@
+/*
+ * Copyright (c) 2022, AcmeStack
+ * All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package logfactory
+
+import (
+	"github.com/acmestack/log4go/util"
+	"sort"
+	"strings"
+	"sync/atomic"
+)
+
+// nameLevel Logger名称前缀对应的日志级别
+type nameLevel struct {
+	prefix string
+	level  Level
+}
+
+// MatchName 判断Logger名称是否匹配前缀，前缀以"."分隔，如前缀a.b匹配a.b及a.b.c，不匹配a.bc
+func MatchName(name, prefix string) bool {
+	if !strings.HasPrefix(name, prefix) {
+		return false
+	}
+	return len(name) == len(prefix) || name[len(prefix)] == '.'
+}
+
+func (l *logging) loadNameLevels() []nameLevel {
+	v, _ := l.nameLevels.Load().([]nameLevel)
+	return v
+}
+
+// levelOf 获得名称对应的日志级别，匹配最长的前缀，没有匹配时为Logging的日志级别
+func (l *logging) levelOf(levels []nameLevel, name string) Level {
+	for _, v := range levels {
+		if MatchName(name, v.prefix) {
+			return v.level
+		}
+	}
+	return atomic.LoadInt32(&l.level)
+}
+
+// isEnabled 根据附加信息中的Logger名称（NameKey）及强制日志级别（ForceLevelKey）判断日志是否输出
+func (l *logging) isEnabled(level Level, keyValues util.KeyValues) bool {
+	levels := l.loadNameLevels()
+	if len(levels) == 0 || keyValues == nil {
+		if l.IsEnabled(level) {
+			return true
+		}
+	} else {
+		name, _ := keyValues.Get(NameKey).(string)
+		if l.levelOf(levels, name) >= level {
+			return true
+		}
+	}
+	return ForceEnabled(keyValues, level)
+}
+
+func (l *logging) GetLogLevel() Level {
+	return atomic.LoadInt32(&l.level)
+}
+
+func (l *logging) IsEnabledByName(name string, severityLevel Level) bool {
+	return l.levelOf(l.loadNameLevels(), name) >= severityLevel
+}
+
+func (l *logging) SetLogLevelByName(prefix string, severityLevel Level) {
+	if prefix == "" {
+		l.SetLogLevel(severityLevel)
+		return
+	}
+	l.updateNameLevels(func(levels []nameLevel) []nameLevel {
+		for i := range levels {
+			if levels[i].prefix == prefix {
+				levels[i].level = severityLevel
+				return levels
+			}
+		}
+		return append(levels, nameLevel{prefix: prefix, level: severityLevel})
+	})
+}
+
+func (l *logging) RemoveLogLevelByName(prefix string) {
+	l.updateNameLevels(func(levels []nameLevel) []nameLevel {
+		for i := range levels {
+			if levels[i].prefix == prefix {
+				return append(levels[:i], levels[i+1:]...)
+			}
+		}
+		return levels
+	})
+}
+
+func (l *logging) GetLogLevelsByName() map[string]Level {
+	levels := l.loadNameLevels()
+	ret := make(map[string]Level, len(levels))
+	for _, v := range levels {
+		ret[v.prefix] = v.level
+	}
+	return ret
+}
+
+// updateNameLevels 复制后修改名称前缀的日志级别，按前缀长度降序排列以便匹配最长的前缀
+func (l *logging) updateNameLevels(f func(levels []nameLevel) []nameLevel) {
+	l.nameLevelLock.Lock()
+	defer l.nameLevelLock.Unlock()
+
+	levels := f(append([]nameLevel(nil), l.loadNameLevels()...))
+	sort.SliceStable(levels, func(i, j int) bool {
+		return len(levels[i].prefix) > len(levels[j].prefix)
+	})
+	l.nameLevels.Store(levels)
+}
@@ -17,12 +17,15 @@
 
 package logfactory
 
+import "context"
+
 // LogDebug interface
 type LogDebug interface {
 	DebugEnabled() bool
 	Debug(args ...interface{})
 	DebugLn(args ...interface{})
 	DebugF(fmt string, args ...interface{})
+	DebugT(template string, args ...interface{})
 }
 
 // LogInfo interface
@@ -31,6 +34,7 @@ type LogInfo interface {
 	Info(args ...interface{})
 	InfoLn(args ...interface{})
 	InfoF(fmt string, args ...interface{})
+	InfoT(template string, args ...interface{})
 }
 
 // LogWarn interface
@@ -39,6 +43,7 @@ type LogWarn interface {
 	Warn(args ...interface{})
 	WarnLn(args ...interface{})
 	WarnF(fmt string, args ...interface{})
+	WarnT(template string, args ...interface{})
 }
 
 // LogError interface
@@ -47,6 +52,7 @@ type LogError interface {
 	Error(args ...interface{})
 	ErrorLn(args ...interface{})
 	ErrorF(fmt string, args ...interface{})
+	ErrorT(template string, args ...interface{})
 }
 
 // LogPanic interface
@@ -55,6 +61,7 @@ type LogPanic interface {
 	Panic(args ...interface{})
 	PanicLn(args ...interface{})
 	PanicF(fmt string, args ...interface{})
+	PanicT(template string, args ...interface{})
 }
 
 // LogFatal interface
@@ -63,6 +70,16 @@ type LogFatal interface {
 	Fatal(args ...interface{})
 	FatalLn(args ...interface{})
 	FatalF(fmt string, args ...interface{})
+	FatalT(template string, args ...interface{})
+}
+
+// LogRecover interface
+type LogRecover interface {
+	// RecoverAndLog 捕获panic并输出ERROR级别日志，包括panic的值及发生panic协程的堆栈，需直接defer调用：
+	// defer logger.RecoverAndLog()
+	RecoverAndLog()
+	// Go 在新的协程中执行f，f发生panic时输出ERROR级别日志，不会导致程序崩溃
+	Go(f func())
 }
 
 // Logger interface 实现了常用的日志方法
@@ -75,6 +92,8 @@ type Logger interface {
 	LogPanic
 	// LogFatal Fatal level log interface, please note that it will trigger the program exit
 	LogFatal
+	// LogRecover panic recovery helpers
+	LogRecover
 
 	// WithName 附加日志名称，注意会附加父Logger的名称，格式为：父Logger名称 + '.' + name
 	WithName(name string) Logger
@@ -84,4 +103,14 @@ type Logger interface {
 
 	// WithDepth 配置日志的调用深度，注意会在父Logger的基础上调整深度
 	WithDepth(depth int) Logger
+
+	// WithContext 获得附加ctx中日志信息的Logger，如WithForceLevel标记的强制日志级别
+	WithContext(ctx context.Context) Logger
+
+	// WithStack 获得输出日志时附加当前协程堆栈（StackKey）的Logger，如：logger.WithStack().Error("failed")
+	WithStack() Logger
+
+	// WithGroup 获得分组的Logger，之后WithFields附加信息的键添加分组前缀，嵌套分组以util.GroupSeparator连接，
+	// 如：logger.WithGroup("http").WithFields("status", 200)附加http.status，name为空时返回原Logger
+	WithGroup(name string) Logger
 }
@@ -17,7 +17,12 @@
 
 package logfactory
 
-import "github.com/acmestack/log4go/util"
+import (
+	"context"
+	"github.com/acmestack/log4go/util"
+	"sync"
+	"sync/atomic"
+)
 
 type LoggerFactoryI interface {
 	// GetLogger 根据参数获得Logger
@@ -33,24 +38,30 @@ type LoggerFactoryI interface {
 	// GetLogging 获得Factory的Logging（线程安全），可用来配置Logging
 	// 也可以通过wrap Logging达到控制日志级别、日志输出格式的目的
 	GetLogging() Logging
+
+	// Flush 将Factory的Logging中所有Writer缓存的日志写入（线程安全），超时由ctx控制
+	Flush(ctx context.Context) error
+
+	// Close 将Factory的Logging中所有Writer缓存的日志写入并关闭Writer（线程安全），超时由ctx控制
+	Close(ctx context.Context) error
 }
 
 type LoggerFactory struct {
-	Value            util.Value
+	Value            util.TypedValue[Logging]
 	SimplifyNameFunc func(string) string
 }
 
-var defaultFactory util.Value = util.NewSimpleValue(NewFactory(DefaultLogging()))
+var defaultFactory = util.NewAtomic[LoggerFactoryI](NewFactory(DefaultLogging()))
 
 func NewDefaultFactory(opts ...LoggingOpt) *LoggerFactory {
 	return NewFactory(NewLogging(opts...))
 }
 
 func NewFactory(logging Logging) *LoggerFactory {
-	return NewFactoryWithValue(util.NewAtomicValue(logging))
+	return NewFactoryWithValue(util.NewAtomic[Logging](logging))
 }
 
-func NewFactoryWithValue(v util.Value) *LoggerFactory {
+func NewFactoryWithValue(v util.TypedValue[Logging]) *LoggerFactory {
 	ret := &LoggerFactory{
 		Value: v,
 	}
@@ -58,33 +69,64 @@ func NewFactoryWithValue(v util.Value) *LoggerFactory {
 }
 
 func (fac *LoggerFactory) GetLogging() Logging {
-	return fac.Value.Load().(Logging)
+	return fac.Value.Load()
 }
 
 func (fac *LoggerFactory) GetLogger(o ...interface{}) Logger {
 	name := util.GetObjectName(fac.SimplifyNameFunc, o...)
-	return defaultLogger(fac.Value.Load().(Logging), nil, name)
+	return defaultLogger(fac.Value.Load(), nil, name)
 }
 
 func (fac *LoggerFactory) Reset(logging Logging) LoggerFactoryI {
 	fac.Value.Store(logging)
+	// 可能为全局默认LoggerFactory，使缓存的Logger重新获取
+	atomic.AddUint64(&generation, 1)
 	return fac
 }
 
-// ResetFactory 重新配置全局的默认LoggerFactory，该方法同时会重置全局的默认Logging
-// 由于线程安全性受defaultLogging、defaultFactory初始化（调用InitOnce）的Value决定，
-// 所以需要确定是否确实需要调用该方法重置Logging，并保证Value线程安全
+func (fac *LoggerFactory) Flush(ctx context.Context) error {
+	return fac.GetLogging().Flush(ctx)
+}
+
+func (fac *LoggerFactory) Close(ctx context.Context) error {
+	return fac.GetLogging().Close(ctx)
+}
+
+// resetLock 保证重置全局默认LoggerFactory及Logging时两者一致
+var resetLock sync.Mutex
+
+// generation 全局默认LoggerFactory及Logging的版本，每次重置后增加
+var generation uint64
+
+// Generation 获得全局默认LoggerFactory及Logging的版本，每次ResetFactory、ResetLogging及LoggerFactory.Reset后增加，
+// 可用于缓存通过全局默认LoggerFactory获得的Logger：先获得版本再获取Logger，版本变化后重新获取
+func Generation() uint64 {
+	return atomic.LoadUint64(&generation)
+}
+
+// ResetFactory 重新配置全局的默认LoggerFactory，该方法同时会重置全局的默认Logging（线程安全）
 func ResetFactory(fac LoggerFactoryI) {
+	resetLock.Lock()
+	defer resetLock.Unlock()
+
 	defaultFactory.Store(fac)
-	ResetLogging(fac.GetLogging())
+	defaultLogging.Store(fac.GetLogging())
+	atomic.AddUint64(&generation, 1)
 }
 
-//// ResetLogging 重新配置全局的默认Logging，该方法同时会重置全局的默认LoggerFactory的Logging
-//// 由于线程安全性受defaultLogging、defaultFactory初始化（调用InitOnce）的Value决定，
-//// 所以需要确定是否确实需要调用该方法重置Logging，并保证Value线程安全
+// ResetLogging 重新配置全局的默认Logging，该方法同时会重置全局的默认LoggerFactory的Logging（线程安全）
 func ResetLogging(logging Logging) {
-	GetLogging().Store(logging)
-	defaultFactory.Load().(LoggerFactoryI).Reset(GetLogging().Load().(Logging))
+	resetLock.Lock()
+	defer resetLock.Unlock()
+
+	defaultLogging.Store(logging)
+	defaultFactory.Load().Reset(logging)
+	atomic.AddUint64(&generation, 1)
+}
+
+// GetFactory 获得全局默认LoggerFactory
+func GetFactory() LoggerFactoryI {
+	return defaultFactory.Load()
 }
 
 // GetLogger 通过全局默认LoggerFactory获取Logger
@@ -93,5 +135,15 @@ func ResetLogging(logging Logging) {
 // 		o为string时，使用string值作为Logger名称
 //		o为其他类型时，取package path + type name作为Logger名称，以"."分隔，如g.x.x.t.TestStructInTest
 func GetLogger(o ...interface{}) Logger {
-	return defaultFactory.Load().(LoggerFactoryI).GetLogger(o...)
+	return defaultFactory.Load().GetLogger(o...)
+}
+
+// Flush 将全局默认LoggerFactory中所有Writer缓存的日志写入，超时由ctx控制
+func Flush(ctx context.Context) error {
+	return defaultFactory.Load().Flush(ctx)
+}
+
+// Close 将全局默认LoggerFactory中所有Writer缓存的日志写入并关闭Writer，超时由ctx控制，通常在程序退出前调用
+func Close(ctx context.Context) error {
+	return defaultFactory.Load().Close(ctx)
 }
@@ -19,15 +19,17 @@ package logfactory
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"github.com/acmestack/log4go/util"
 	"io"
 	"os"
+	"reflect"
 	"runtime"
 	"strings"
 	"sync"
 	"sync/atomic"
-	"syscall"
 	"time"
 )
 
@@ -78,6 +80,16 @@ const (
 	ContentKey = "LogContent"
 	// NameKey LogName
 	NameKey = "LogName"
+	// PanicKey LogPanic
+	PanicKey = "LogPanic"
+	// StackKey LogStack
+	StackKey = "LogStack"
+	// ErrorKey LogError
+	ErrorKey = "LogError"
+	// ForceLevelKey LogForceLevel，值为Level，标记强制输出的日志级别，不会输出
+	ForceLevelKey = "LogForceLevel"
+	// TemplateKey LogTemplate，LogT输出日志的原始消息模板
+	TemplateKey = "LogTemplate"
 )
 
 var (
@@ -121,11 +133,24 @@ var LogTag = map[Level]string{
 	FATAL: "FATAL",
 }
 
+// ParseLevel 将级别名称（不区分大小写）转换为级别
+func ParseLevel(name string) (Level, error) {
+	name = strings.ToUpper(name)
+	for k, v := range LogTag {
+		if v == name {
+			return k, nil
+		}
+	}
+	return DEBUG, fmt.Errorf("unknown level: %s", name)
+}
+
 // 默认值
 var (
 	DefaultColorFlag     = DisableColor
 	DefaultPrintFileFlag = CallerShortFile
 	DefaultFatalNoTrace  = false
+	DefaultFlushTimeout  = 3 * time.Second
+	DefaultRetryCooldown = 10 * time.Second
 	DefaultLevel         = INFO
 	DefaultWriters       = map[Level]io.Writer{
 		DEBUG: os.Stdout,
@@ -147,6 +172,11 @@ type Logging interface {
 
 	LogLn(level Level, depth int, keyValues util.KeyValues, args ...interface{})
 
+	// LogT 使用消息模板输出日志，如："user {User} logged in from {IP}"，占位符按首次出现的顺序绑定args，
+	// 渲染到日志内容中并作为附加信息字段输出，原始模板作为TemplateKey字段输出。
+	// 占位符与已有附加信息同名时按键冲突策略处理（内置Logging默认覆盖已有的值）
+	LogT(level Level, depth int, keyValues util.KeyValues, template string, args ...interface{})
+
 	// SetFormatter setting Formatter
 	SetFormatter(f util.Formatter)
 
@@ -156,6 +186,22 @@ type Logging interface {
 	// IsEnabled 判断参数级别是否会输出（线程安全）
 	IsEnabled(severityLevel Level) bool
 
+	// GetLogLevel 获得日志严重级别（线程安全）
+	GetLogLevel() Level
+
+	// SetLogLevelByName 设置名称前缀为prefix的Logger的日志严重级别，前缀以"."分隔，匹配最长的前缀（线程安全）
+	// 如：前缀a.b匹配名称为a.b、a.b.c的Logger，prefix为空时同SetLogLevel
+	SetLogLevelByName(prefix string, severityLevel Level)
+
+	// RemoveLogLevelByName 删除名称前缀的日志严重级别，匹配的Logger恢复使用Logging的日志级别（线程安全）
+	RemoveLogLevelByName(prefix string)
+
+	// GetLogLevelsByName 获得所有名称前缀的日志严重级别（线程安全）
+	GetLogLevelsByName() map[string]Level
+
+	// IsEnabledByName 判断名称为name的Logger参数级别是否会输出（线程安全）
+	IsEnabledByName(name string, severityLevel Level) bool
+
 	// SetOutput 设置输出的Writer，注意该方法会将所有级别都配置为参数writer（线程安全）
 	SetOutput(w io.Writer)
 
@@ -167,22 +213,87 @@ type Logging interface {
 
 	// Clone 获得一个clone的对象（线程安全）
 	Clone() Logging
+
+	// Flush 将所有级别Writer中缓存的日志写入（线程安全），超时由ctx控制。
+	// 注意超时返回后执行Writer Flush的协程仍会继续运行直到其返回
+	Flush(ctx context.Context) error
+
+	// Close 将所有级别Writer中缓存的日志写入并关闭Writer（线程安全），超时由ctx控制。注意不会关闭标准输出及标准错误。
+	// Close后的日志不再写入，计入FailedWrites并以ErrLoggingClosed调用ErrorHandler；
+	// 超时返回后执行Writer Flush、Close的协程仍会继续运行直到其返回
+	Close(ctx context.Context) error
+
+	// FailedWrites 获得格式化或写入Writer失败的次数（线程安全）
+	FailedWrites() uint64
+}
+
+type flusher interface {
+	Flush() error
+}
+
+// levelWriter 可感知日志级别的Writer，如writer.LevelRouter
+type levelWriter interface {
+	WriteLevel(level Level, data []byte) (int, error)
+}
+
+// RecordWriter 可获得结构化日志记录的Writer，如appender.MemoryAppender，内置Logging实现写入时调用WriteRecord代替Write
+type RecordWriter interface {
+	// WriteRecord record包括内置的LogTime、LogLevel等及附加信息（不可修改，需保存时使用Clone），data为格式化后的日志
+	WriteRecord(level Level, record util.KeyValues, data []byte) (int, error)
 }
 
 type ExitFunc func(code int)
 type PanicFunc func(interface{})
 
+// ErrorHandler 日志格式化或写入失败时的处理函数
+type ErrorHandler func(level Level, err error)
+
+// ErrLoggingClosed Logging已Close，日志不再写入
+var ErrLoggingClosed = errors.New("Logging is closed ")
+
+// breaker 写入失败后在冷却时间内跳过原Writer
+type breaker struct {
+	openUntil int64
+}
+
+func (b *breaker) isOpen() bool {
+	until := atomic.LoadInt64(&b.openUntil)
+	return until != 0 && time.Now().UnixNano() < until
+}
+
+func (b *breaker) open(cooldown time.Duration) {
+	atomic.StoreInt64(&b.openUntil, time.Now().Add(cooldown).UnixNano())
+}
+
 type logging struct {
 	timeFormatter   func(t time.Time) string
 	callerFormatter func(file string, line int, funcName string) string
 	exitFunc        ExitFunc
+	exitCode        int
+	fatalMode       FatalMode
+	shutdownTimeout time.Duration
 	panicFunc       PanicFunc
+	panicValueFunc  PanicValueFunc
 	formatter       atomic.Value
 	colorFlag       int
 	fileFlag        int
-	fatalNoTrace    bool
-
-	level Level
+	stackModes      [DEBUG + 1]StackMode
+	stackFilter     StackFilter
+	flushTimeout    time.Duration
+	errorHandler    ErrorHandler
+	redactor        Redactor
+	keyCollision    KeyCollision
+	collisionAffix  string
+	templates       *templateCache
+	fallback        io.Writer
+	retryCooldown   time.Duration
+	breakers        [DEBUG + 1]breaker
+	failedWrites    uint64
+	closed          int32
+
+	level         Level
+	nameLevels    atomic.Value
+	nameLevelLock sync.Mutex
 
 	writers sync.Map
 
@@ -196,14 +307,15 @@ func SimplifyNameFirstLetter(s string) string {
 	return s[:1]
 }
 
-var defaultLogging util.Value = util.NewSimpleValue(NewLogging())
+var defaultLogging = util.NewAtomic[Logging](NewLogging())
 
 // DefaultLogging 获得默认Logging
 func DefaultLogging() Logging {
-	return defaultLogging.Load().(Logging)
+	return defaultLogging.Load()
 }
 
-func GetLogging() util.Value {
+// GetLogging 获得存储全局默认Logging的Value，重新配置请使用ResetLogging
+func GetLogging() util.TypedValue[Logging] {
 	return defaultLogging
 }
 
@@ -212,18 +324,27 @@ func NewLogging(opts ...LoggingOpt) Logging {
 		timeFormatter:   timeFormat,
 		callerFormatter: callerFormat,
 		exitFunc:        defaultExit,
+		exitCode:        DefaultExitCode,
+		shutdownTimeout: DefaultShutdownTimeout,
 		panicFunc:       defaultPanic,
+		panicValueFunc:  NewPanicError,
 		//formatter:     nil,
-		colorFlag:    DefaultColorFlag,
-		fileFlag:     DefaultPrintFileFlag,
-		fatalNoTrace: DefaultFatalNoTrace,
-		level:        DefaultLevel,
+		colorFlag:     DefaultColorFlag,
+		fileFlag:      DefaultPrintFileFlag,
+		stackFilter:   DefaultStackFilter,
+		flushTimeout:  DefaultFlushTimeout,
+		retryCooldown: DefaultRetryCooldown,
+		templates:     newTemplateCache(DefaultTemplateCacheSize),
+		level:         DefaultLevel,
 
 		bufPool: sync.Pool{New: func() interface{} {
 			return bytes.NewBuffer(nil)
 		}},
 	}
 
+	if !DefaultFatalNoTrace {
+		ret.stackModes[FATAL] = StackAll
+	}
 	for k, v := range DefaultWriters {
 		ret.writers.Store(k, v)
 	}
@@ -278,33 +399,164 @@ func (l *logging) format(writer io.Writer, level Level, depth int, keyValues uti
 		resetColor = ResetColor
 	}
 
+	buf := l.getBuffer()
+	defer l.putBuffer(buf)
+
+	now := time.Now()
+	log = l.redactMessage(log)
+	stack := l.captureStack(level, depth, keyValues)
 	formatter := l.formatter.Load()
+	_, needRecord := writer.(RecordWriter)
+	var record util.KeyValues
+	if formatter != nil || needRecord {
+		record = l.record(now, level, caller, keyValues, stack, log)
+	}
 	if formatter != nil {
-		innerKvs := util.NewKeyValues()
-		_ = innerKvs.Add(TimestampKey, time.Now(), LevelKey, LogTag[level], CallerKey, caller)
-		_, _ = util.MergeKeyValues(innerKvs, keyValues)
-		if log == "\n" {
-			log = ""
+		if err := formatter.(util.Formatter).Format(buf, record); err != nil {
+			atomic.AddUint64(&l.failedWrites, 1)
+			l.handleError(level, err)
+			return
+		}
+	} else {
+		_, _ = fmt.Fprintf(buf, "%s [%s%s%s] %s ",
+			l.timeFormatter(now), lvColor, LogTag[level], resetColor, caller)
+		l.formatKeyValues(buf, keyValues)
+		buf.WriteString(log)
+		if stack == nil && keyValues != nil {
+			// 文本格式时输出附加信息中的堆栈（如LogRecovered获得的panic堆栈）或error自带的堆栈
+			if s, ok := keyValues.Get(StackKey).(Stack); ok {
+				stack = s
+			} else if e, ok := keyValues.Get(ErrorKey).(*ErrorInfo); ok {
+				if s := e.StackTrace(); s != nil {
+					stack = s
+				}
+			}
+		}
+		if stack != nil {
+			if buf.Len() > 0 && buf.Bytes()[buf.Len()-1] != '\n' {
+				buf.WriteByte('\n')
+			}
+			_, _ = fmt.Fprint(buf, stack)
 		}
-		_ = innerKvs.Add(ContentKey, log)
-		_ = formatter.(util.Formatter).Format(writer, innerKvs)
+	}
+	l.write(writer, level, record, buf.Bytes())
+}
+
+// record 获得结构化的日志记录，包括内置的LogTime、LogLevel等及附加信息，用于Formatter及RecordWriter
+func (l *logging) record(now time.Time, level Level, caller string, keyValues util.KeyValues, stack interface{}, log string) util.KeyValues {
+	ret := util.NewKeyValues(TimestampKey, now, LevelKey, LogTag[level], CallerKey, caller)
+	if keyValues != nil {
+		keyValues.Range(func(key string, value interface{}) bool {
+			if k, ok := l.fieldKey(level, key, keyValues); ok {
+				_ = ret.Add(k, l.redactValue(key, value))
+			}
+			return true
+		})
+	}
+	_ = ret.Remove(ForceLevelKey)
+	if stack != nil {
+		_ = ret.Add(StackKey, stack)
+	} else if _, ok := ret.Get(StackKey).(StackMode); ok {
+		_ = ret.Remove(StackKey)
+	}
+	if log == "\n" {
+		log = ""
+	}
+	_ = ret.Add(ContentKey, log)
+	return ret
+}
+
+// captureStack 根据级别的配置及附加信息中的StackKey（WithStack）获得堆栈，不需要时返回nil
+func (l *logging) captureStack(level Level, depth int, keyValues util.KeyValues) interface{} {
+	mode := StackOff
+	if level >= FATAL && level <= DEBUG {
+		mode = l.stackModes[level]
+	}
+	if keyValues != nil {
+		if m, ok := keyValues.Get(StackKey).(StackMode); ok && m > mode {
+			mode = m
+		}
+	}
+	switch mode {
+	case StackCurrent:
+		// skip captureStack, format, Log
+		return CaptureStack(depth+3, l.stackFilter)
+	case StackAll:
+		return string(stacks(true))
+	}
+	return nil
+}
+
+// write 写入level对应的Writer，失败时调用ErrorHandler，如果配置了备用Writer则写入备用Writer，
+// 且在RetryCooldown时间内直接写入备用Writer，之后再重新尝试原Writer
+func (l *logging) write(writer io.Writer, level Level, record util.KeyValues, data []byte) {
+	if atomic.LoadInt32(&l.closed) != 0 {
+		atomic.AddUint64(&l.failedWrites, 1)
+		l.handleError(level, ErrLoggingClosed)
+		return
+	}
+
+	var b *breaker
+	if l.fallback != nil && level >= FATAL && level <= DEBUG {
+		b = &l.breakers[level]
+		if b.isOpen() {
+			l.writeFallback(level, data)
+			return
+		}
+	}
+
+	var err error
+	if rw, ok := writer.(RecordWriter); ok {
+		_, err = rw.WriteRecord(level, record, data)
+	} else if lw, ok := writer.(levelWriter); ok {
+		_, err = lw.WriteLevel(level, data)
 	} else {
-		_, _ = writer.Write([]byte(fmt.Sprintf("%s [%s%s%s] %s %s%s",
-			l.timeFormatter(time.Now()), lvColor, LogTag[level], resetColor, caller, l.formatKeyValues(keyValues), log)))
+		_, err = writer.Write(data)
+	}
+	if err == nil {
+		return
+	}
+	atomic.AddUint64(&l.failedWrites, 1)
+	l.handleError(level, err)
+	if l.fallback != nil {
+		if b != nil {
+			b.open(l.retryCooldown)
+		}
+		l.writeFallback(level, data)
+	}
+}
+
+func (l *logging) writeFallback(level Level, data []byte) {
+	if _, err := l.fallback.Write(data); err != nil {
+		atomic.AddUint64(&l.failedWrites, 1)
+		l.handleError(level, err)
 	}
 }
 
-func (l *logging) formatKeyValues(keyValues util.KeyValues) string {
+func (l *logging) handleError(level Level, err error) {
+	if l.errorHandler != nil {
+		l.errorHandler(level, err)
+	}
+}
+
+func (l *logging) FailedWrites() uint64 {
+	return atomic.LoadUint64(&l.failedWrites)
+}
+
+func (l *logging) formatKeyValues(buf *bytes.Buffer, keyValues util.KeyValues) {
 	if keyValues == nil || keyValues.Len() == 0 {
-		return ""
+		return
 	}
 
-	buf := bytes.Buffer{}
-	for _, k := range keyValues.Keys() {
-		buf.WriteString(l.formatValue(keyValues.Get(k)))
+	keyValues.Range(func(key string, value interface{}) bool {
+		// panic的值已在日志内容中输出
+		if key == StackKey || key == ForceLevelKey || key == TemplateKey || key == PanicKey || isTemplateField(keyValues, key) {
+			return true
+		}
+		buf.WriteString(l.formatValue(l.redactValue(key, value)))
 		buf.WriteByte(' ')
-	}
-	return buf.String()
+		return true
+	})
 }
 
 func (l *logging) formatValue(o interface{}) string {
@@ -321,7 +573,7 @@ func (l *logging) formatValue(o interface{}) string {
 }
 
 func (l *logging) LogF(level Level, depth int, keyValues util.KeyValues, format string, args ...interface{}) {
-	if !l.IsEnabled(level) {
+	if !l.isEnabled(level, keyValues) {
 		return
 	}
 
@@ -331,48 +583,67 @@ func (l *logging) LogF(level Level, depth int, keyValues util.KeyValues, format
 			format = format + "\n"
 		}
 	}
-	logInfo := fmt.Sprintf(format, args...)
+	args, errInfo := extractErrors(resolveArgs(args), true)
+	var logInfo string
+	if strings.Contains(format, "%w") {
+		// %w包装的error作为ErrorKey字段输出
+		var wrapped *ErrorInfo
+		logInfo, wrapped = wrappedError(format, args...)
+		if errInfo == nil {
+			errInfo = wrapped
+		}
+	} else {
+		logInfo = fmt.Sprintf(format, args...)
+	}
+	keyValues = withError(keyValues, errInfo)
 	w := l.selectWriter(level)
 	l.format(w, level, depth, keyValues, logInfo)
 
 	if level == PANIC {
-		l.panicFunc(util.NewKeyValues(ContentKey, logInfo))
+		l.flushBeforeExit()
+		l.panicFunc(l.panicValueFunc(logInfo, keyValues))
 	} else if level <= FATAL {
-		l.processFatal(w)
+		l.processFatal()
 	}
 
 	//l.output(level, buf)
 }
 
 func (l *logging) Log(level Level, depth int, keyValues util.KeyValues, args ...interface{}) {
-	if !l.IsEnabled(level) {
+	if !l.isEnabled(level, keyValues) {
 		return
 	}
 
+	args, errInfo := extractErrors(resolveArgs(args), false)
+	keyValues = withError(keyValues, errInfo)
 	logInfo := fmt.Sprint(args...)
 	w := l.selectWriter(level)
 	l.format(w, level, depth, keyValues, logInfo)
 
 	if level == PANIC {
-		l.panicFunc(util.NewKeyValues(ContentKey, logInfo))
+		l.flushBeforeExit()
+		l.panicFunc(l.panicValueFunc(logInfo, keyValues))
 	} else if level <= FATAL {
-		l.processFatal(w)
+		l.processFatal()
 	}
 }
 
 func (l *logging) LogLn(level Level, depth int, keyValues util.KeyValues, args ...interface{}) {
-	if !l.IsEnabled(level) {
+	if !l.isEnabled(level, keyValues) {
 		return
 	}
 
+	args, errInfo := extractErrors(resolveArgs(args), false)
+	keyValues = withError(keyValues, errInfo)
 	logInfo := fmt.Sprintln(args...)
 	w := l.selectWriter(level)
 	l.format(w, level, depth, keyValues, logInfo)
 
 	if level == PANIC {
-		l.panicFunc(util.NewKeyValues(ContentKey, logInfo))
+		l.flushBeforeExit()
+		l.panicFunc(l.panicValueFunc(logInfo, keyValues))
 	} else if level <= FATAL {
-		l.processFatal(w)
+		l.processFatal()
 	}
 }
 
@@ -393,23 +664,104 @@ func (l *logging) putBuffer(buf *bytes.Buffer) {
 	l.bufPool.Put(buf)
 }
 
-func (l *logging) processFatal(writer io.Writer) {
-	if !l.fatalNoTrace {
-		trace := stacks(true)
-		writer.Write(trace)
+func (l *logging) flushBeforeExit() {
+	ctx, cancel := context.WithTimeout(context.Background(), l.flushTimeout)
+	defer cancel()
+	_ = l.Flush(ctx)
+}
+
+func (l *logging) Flush(ctx context.Context) error {
+	var ret error
+	for _, w := range l.distinctWriters() {
+		if f, ok := w.(flusher); ok {
+			if err := runWithContext(ctx, f.Flush); err != nil && ret == nil {
+				ret = err
+			}
+		}
+	}
+	return ret
+}
+
+func (l *logging) Close(ctx context.Context) error {
+	atomic.StoreInt32(&l.closed, 1)
+	ret := l.Flush(ctx)
+	for _, w := range l.distinctWriters() {
+		if w == os.Stdout || w == os.Stderr {
+			continue
+		}
+		if c, ok := w.(io.Closer); ok {
+			if err := runWithContext(ctx, c.Close); err != nil && ret == nil {
+				ret = err
+			}
+		}
+	}
+	return ret
+}
+
+func (l *logging) distinctWriters() []io.Writer {
+	var ret []io.Writer
+	l.writers.Range(func(key, value interface{}) bool {
+		if w, ok := value.(io.Writer); ok && w != nil {
+			ret = appendDistinct(ret, w)
+		}
+		return true
+	})
+	if l.fallback != nil {
+		ret = appendDistinct(ret, l.fallback)
+	}
+	return ret
+}
+
+// appendDistinct 将w添加到ws中，已存在相同的Writer时不添加
+func appendDistinct(ws []io.Writer, w io.Writer) []io.Writer {
+	if reflect.TypeOf(w).Comparable() {
+		for _, v := range ws {
+			if reflect.TypeOf(v).Comparable() && v == w {
+				return ws
+			}
+		}
+	}
+	return append(ws, w)
+}
+
+// runWithContext 在新协程中执行f，ctx超时或取消时直接返回ctx.Err()，此时执行f的协程不会被终止，仍会运行直到f返回
+func runWithContext(ctx context.Context, f func() error) error {
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- f()
+	}()
+	select {
+	case err := <-errChan:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
 	}
-	l.exitFunc(-1)
 }
 
 func (l *logging) Clone() Logging {
 	ret := &logging{
 		timeFormatter:   l.timeFormatter,
 		callerFormatter: l.callerFormatter,
+		exitFunc:        l.exitFunc,
+		exitCode:        l.exitCode,
+		fatalMode:       l.fatalMode,
+		shutdownTimeout: l.shutdownTimeout,
+		panicFunc:       l.panicFunc,
+		panicValueFunc:  l.panicValueFunc,
 		//formatter:     l.formatter,
-		colorFlag:    l.colorFlag,
-		fileFlag:     l.fileFlag,
-		fatalNoTrace: l.fatalNoTrace,
-		level:        l.level,
+		colorFlag:      l.colorFlag,
+		fileFlag:       l.fileFlag,
+		stackModes:     l.stackModes,
+		stackFilter:    l.stackFilter,
+		flushTimeout:   l.flushTimeout,
+		errorHandler:   l.errorHandler,
+		redactor:       l.redactor,
+		keyCollision:   l.keyCollision,
+		collisionAffix: l.collisionAffix,
+		templates:      l.templates,
+		fallback:       l.fallback,
+		retryCooldown:  l.retryCooldown,
+		level:          l.level,
 		//writers:       map[Level]io.Writer{},
 
 		bufPool: sync.Pool{New: func() interface{} {
@@ -417,6 +769,9 @@ func (l *logging) Clone() Logging {
 		}},
 	}
 	ret.formatter.Store(l.formatter.Load())
+	if levels := l.loadNameLevels(); levels != nil {
+		ret.nameLevels.Store(levels)
+	}
 	l.writers.Range(func(key, value interface{}) bool {
 		ret.writers.Store(key, value)
 		return true
@@ -567,7 +922,7 @@ func SetCallerFormatter(f func(file string, line int, funcName string) string) f
 	}
 }
 
-// SetExitFunc 配置内置Logging Fatal退出处理函数
+// SetExitFunc 配置内置Logging Fatal退出处理函数，默认为os.Exit，测试时可替换以避免退出
 func SetExitFunc(f ExitFunc) func(*logging) {
 	return func(logging *logging) {
 		logging.exitFunc = f
@@ -581,25 +936,6 @@ func SetPanicFunc(f PanicFunc) func(*logging) {
 	}
 }
 
-func defaultExit(code int) {
-	pid := os.Getpid()
-	p, err := os.FindProcess(pid)
-	if err != nil {
-		os.Exit(code)
-	} else {
-		if err = p.Signal(os.Interrupt); err != nil {
-			// Per https://golang.org/pkg/os/#Signal, “Interrupt is not implemented on
-			// Windows; using it with os.Process.Signal will return an error.”
-			// Fall back to Kill instead.
-			if err = p.Signal(syscall.SIGTERM); err != nil {
-				if err = p.Kill(); err != nil {
-					os.Exit(code)
-				}
-			}
-		}
-	}
-}
-
 func defaultPanic(v interface{}) {
 	panic(v)
 }
@@ -640,10 +976,65 @@ func SetCallerFlag(flag int) func(*logging) {
 	}
 }
 
-// SetFatalNoTrace 配置内置Logging实现是否在发生致命错误时打印堆栈，默认打印
+// SetFatalNoTrace 配置内置Logging实现是否在发生致命错误时打印所有协程的堆栈，默认打印
 func SetFatalNoTrace(noTrace bool) func(*logging) {
 	return func(logging *logging) {
-		logging.fatalNoTrace = noTrace
+		if noTrace {
+			logging.stackModes[FATAL] = StackOff
+		} else {
+			logging.stackModes[FATAL] = StackAll
+		}
+	}
+}
+
+// SetStackMode 配置内置Logging实现在输出level级别日志时附加的堆栈（StackKey字段），默认仅FATAL级别输出所有协程的堆栈
+func SetStackMode(level Level, mode StackMode) func(*logging) {
+	return func(logging *logging) {
+		if level >= FATAL && level <= DEBUG {
+			logging.stackModes[level] = mode
+		}
+	}
+}
+
+// SetStackFilter 配置内置Logging实现输出当前协程堆栈时的过滤函数，默认为DefaultStackFilter
+func SetStackFilter(filter StackFilter) func(*logging) {
+	return func(logging *logging) {
+		logging.stackFilter = filter
+	}
+}
+
+// SetPanicValueFunc 配置内置Logging Panic级别日志传递给Panic处理函数的值，默认为*PanicError
+func SetPanicValueFunc(f PanicValueFunc) func(*logging) {
+	return func(logging *logging) {
+		logging.panicValueFunc = f
+	}
+}
+
+// SetFlushTimeout 配置内置Logging实现在Panic、Fatal前写入Writer缓存的超时时间
+func SetFlushTimeout(timeout time.Duration) func(*logging) {
+	return func(logging *logging) {
+		logging.flushTimeout = timeout
+	}
+}
+
+// SetErrorHandler 配置内置Logging实现在日志格式化或写入失败时的处理函数
+func SetErrorHandler(h ErrorHandler) func(*logging) {
+	return func(logging *logging) {
+		logging.errorHandler = h
+	}
+}
+
+// SetFallbackWriter 配置内置Logging实现的备用Writer（如os.Stderr），当对应级别的Writer写入失败时写入备用Writer
+func SetFallbackWriter(w io.Writer) func(*logging) {
+	return func(logging *logging) {
+		logging.fallback = w
+	}
+}
+
+// SetRetryCooldown 配置内置Logging实现在Writer写入失败后，直接写入备用Writer的时间，之后重新尝试原Writer
+func SetRetryCooldown(cooldown time.Duration) func(*logging) {
+	return func(logging *logging) {
+		logging.retryCooldown = cooldown
 	}
 }
 
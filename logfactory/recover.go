@@ -0,0 +1,96 @@
+/*
+ * Copyright (c) 2022, AcmeStack
+ * All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package logfactory
+
+import (
+	"github.com/acmestack/log4go/util"
+	"runtime"
+	"strings"
+)
+
+// PanicValueFunc 根据Panic级别日志的内容及附加信息生成传递给Panic处理函数的值
+type PanicValueFunc func(message string, keyValues util.KeyValues) interface{}
+
+// PanicError Panic级别日志默认触发panic的值
+type PanicError struct {
+	// 日志内容
+	Message string
+	// Logger的附加信息
+	Fields util.KeyValues
+}
+
+func (e *PanicError) Error() string {
+	return strings.TrimSuffix(e.Message, "\n")
+}
+
+// NewPanicError 默认的PanicValueFunc，返回*PanicError
+func NewPanicError(message string, keyValues util.KeyValues) interface{} {
+	var fields util.KeyValues
+	if keyValues != nil {
+		fields = keyValues.Clone()
+	}
+	return &PanicError{
+		Message: message,
+		Fields:  fields,
+	}
+}
+
+// PanicKeyValues 返回只包含日志内容的util.KeyValues（ContentKey），兼容旧版本的PanicValueFunc
+func PanicKeyValues(message string, keyValues util.KeyValues) interface{} {
+	return util.NewKeyValues(ContentKey, message)
+}
+
+// LogRecovered 输出recover获得的panic值及当前协程的堆栈（ERROR级别），供Logger实现RecoverAndLog使用，
+// 需在defer调用的函数中直接调用，日志的调用位置为发生panic的位置
+func LogRecovered(logging Logging, keyValues util.KeyValues, v interface{}) {
+	kvs := util.NewKeyValues()
+	if keyValues != nil {
+		kvs = keyValues.Clone()
+	}
+	// 在defer调用中获得的堆栈即为发生panic协程的堆栈
+	_ = kvs.Add(PanicKey, v, StackKey, CaptureStack(0, DefaultStackFilter))
+	logging.Log(ERROR, panicDepth(), kvs, "recovered from panic: ", v)
+}
+
+// RecoveredLogger 可输出recover获得的panic值的Logger，内置的Logger实现均实现了该接口，
+// 用于在Logger.RecoverAndLog之外（如log.RecoverAndLog）使用Logger的附加信息输出panic
+type RecoveredLogger interface {
+	// LogRecovered 同logfactory.LogRecovered，使用Logger的Logging及附加信息，需在defer调用的函数中直接调用
+	LogRecovered(v interface{})
+}
+
+// panicDepth 计算LogRecovered的调用者到发生panic位置的帧数
+func panicDepth() int {
+	pc := make([]uintptr, 32)
+	// skip runtime.Callers, panicDepth
+	n := runtime.Callers(2, pc)
+	frames := runtime.CallersFrames(pc[:n])
+	panicking := false
+	for depth := 0; ; depth++ {
+		frame, more := frames.Next()
+		if panicking && !strings.HasPrefix(frame.Function, "runtime.") {
+			return depth
+		}
+		if frame.Function == "runtime.gopanic" {
+			panicking = true
+		}
+		if !more {
+			return 2
+		}
+	}
+}
@@ -0,0 +1,87 @@
+/*
+ * Copyright (c) 2022, AcmeStack
+ * All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package logfactory
+
+import "github.com/acmestack/log4go/util"
+
+// Redactable 控制自身日志输出内容的类型，作为日志参数或附加信息的值时输出Redacted的返回值，
+// 如：密码类型返回"******"
+type Redactable interface {
+	Redacted() interface{}
+}
+
+// Redactor 脱敏处理，配置给Logging后（SetRedactor）对附加信息的值及日志内容进行脱敏，实现见redact包
+type Redactor interface {
+	// RedactField 返回附加信息key对应值脱敏后的值
+	RedactField(key string, value interface{}) interface{}
+	// RedactMessage 返回日志内容脱敏后的内容
+	RedactMessage(message string) string
+}
+
+// SetRedactor 配置内置Logging实现的脱敏处理，默认不脱敏
+func SetRedactor(r Redactor) func(*logging) {
+	return func(logging *logging) {
+		logging.redactor = r
+	}
+}
+
+// redactValue 获得附加信息值的输出内容：解析util.LogValuer后脱敏，NameKey等内置字段不处理
+func (l *logging) redactValue(key string, value interface{}) interface{} {
+	switch key {
+	case NameKey, StackKey, ForceLevelKey:
+		return value
+	}
+	value = util.ResolveValue(value)
+	if r, ok := value.(Redactable); ok {
+		value = r.Redacted()
+	}
+	if l.redactor != nil {
+		value = l.redactor.RedactField(key, value)
+	}
+	return value
+}
+
+func (l *logging) redactMessage(message string) string {
+	if l.redactor == nil {
+		return message
+	}
+	return l.redactor.RedactMessage(message)
+}
+
+// resolveArgs 将日志参数中的util.LogValuer替换为LogValue的返回值，Redactable替换为Redacted的返回值，
+// 在级别检查之后调用，不修改原参数
+func resolveArgs(args []interface{}) []interface{} {
+	copied := false
+	for i, v := range args {
+		switch v.(type) {
+		case util.LogValuer, Redactable:
+		default:
+			continue
+		}
+		if !copied {
+			args = append([]interface{}{}, args...)
+			copied = true
+		}
+		v = util.ResolveValue(v)
+		if r, ok := v.(Redactable); ok {
+			v = r.Redacted()
+		}
+		args[i] = v
+	}
+	return args
+}
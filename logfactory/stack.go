@@ -0,0 +1,115 @@
+/*
+ * Copyright (c) 2022, AcmeStack
+ * All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package logfactory
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+)
+
+// StackMode 堆栈的输出方式
+type StackMode int
+
+const (
+	// StackOff 不输出堆栈
+	StackOff StackMode = iota
+	// StackCurrent 输出当前协程的堆栈，StackKey字段的值为Stack
+	StackCurrent
+	// StackAll 输出所有协程的堆栈，StackKey字段的值为runtime.Stack的文本
+	StackAll
+)
+
+// StackFrame 堆栈中的一帧
+type StackFrame struct {
+	Function string `json:"function"`
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+}
+
+// Stack 结构化的堆栈，JsonFormatter输出为数组，TextFormatter输出为多行文本
+type Stack []StackFrame
+
+func (s Stack) String() string {
+	buf := strings.Builder{}
+	for _, f := range s {
+		buf.WriteString(f.Function)
+		buf.WriteString("\n\t")
+		buf.WriteString(f.File)
+		buf.WriteByte(':')
+		buf.WriteString(fmt.Sprint(f.Line))
+		buf.WriteByte('\n')
+	}
+	return buf.String()
+}
+
+// StackFilter 过滤堆栈中的帧，返回false的帧将不会输出
+type StackFilter func(frame StackFrame) bool
+
+const log4goPkgPrefix = "github.com/acmestack/log4go/"
+
+var log4goPkgs = []string{"logfactory.", "ext.", "log.", "util."}
+
+// DefaultStackFilter 默认的StackFilter，过滤log4go自身的帧
+func DefaultStackFilter(frame StackFrame) bool {
+	if !strings.HasPrefix(frame.Function, log4goPkgPrefix) {
+		return true
+	}
+	name := frame.Function[len(log4goPkgPrefix):]
+	for _, v := range log4goPkgs {
+		if strings.HasPrefix(name, v) {
+			return false
+		}
+	}
+	return true
+}
+
+// CaptureStack 获得当前协程的堆栈，skip为跳过的帧数，0表示从CaptureStack的调用者开始
+func CaptureStack(skip int, filter StackFilter) Stack {
+	pc := make([]uintptr, 64)
+	n := runtime.Callers(skip+2, pc)
+	for n == len(pc) {
+		pc = make([]uintptr, len(pc)*2)
+		n = runtime.Callers(skip+2, pc)
+	}
+	return callersStack(pc[:n], filter)
+}
+
+// callersStack 将runtime.Callers格式的程序计数器转换为Stack
+func callersStack(pc []uintptr, filter StackFilter) Stack {
+	if len(pc) == 0 {
+		return nil
+	}
+	frames := runtime.CallersFrames(pc)
+	var ret Stack
+	for {
+		frame, more := frames.Next()
+		f := StackFrame{
+			Function: frame.Function,
+			File:     frame.File,
+			Line:     frame.Line,
+		}
+		if filter == nil || filter(f) {
+			ret = append(ret, f)
+		}
+		if !more {
+			break
+		}
+	}
+	return ret
+}
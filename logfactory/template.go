@@ -0,0 +1,273 @@
+/*
+ * Copyright (c) 2022, AcmeStack
+ * All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package logfactory
+
+import (
+	"container/list"
+	"fmt"
+	"github.com/acmestack/log4go/util"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// DefaultTemplateCacheSize 内置Logging实现按调用位置缓存的消息模板数量上限，见SetTemplateCacheSize
+var DefaultTemplateCacheSize = 1024
+
+// templateToken 消息模板的片段，name不为空时为占位符
+type templateToken struct {
+	text string
+	name string
+}
+
+// messageTemplate 解析后的消息模板，作为TemplateKey字段的值输出原始模板
+type messageTemplate struct {
+	raw    string
+	tokens []templateToken
+	// 按首次出现顺序排列的占位符名称，依次绑定日志参数
+	names []string
+}
+
+// templateEntry 按调用位置缓存的消息模板
+type templateEntry struct {
+	pc       uintptr
+	template *messageTemplate
+}
+
+// templateCache 按调用位置（caller PC）缓存解析后的消息模板，超过size时淘汰最久未使用的调用位置（LRU）
+type templateCache struct {
+	size    int
+	lock    sync.Mutex
+	entries map[uintptr]*list.Element
+	lru     list.List
+}
+
+func newTemplateCache(size int) *templateCache {
+	return &templateCache{size: size, entries: map[uintptr]*list.Element{}}
+}
+
+// get 获得pc处调用的消息模板。同一调用位置传入的模板与缓存不一致时（动态拼接的模板）重新解析并替换缓存，
+// 因此每个调用位置最多占用一个缓存项
+func (c *templateCache) get(pc uintptr, template string) *messageTemplate {
+	if c == nil || c.size <= 0 || pc == 0 {
+		return parseTemplate(template)
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if e, ok := c.entries[pc]; ok {
+		c.lru.MoveToFront(e)
+		entry := e.Value.(*templateEntry)
+		if entry.template.raw != template {
+			entry.template = parseTemplate(template)
+		}
+		return entry.template
+	}
+	t := parseTemplate(template)
+	c.entries[pc] = c.lru.PushFront(&templateEntry{pc: pc, template: t})
+	if c.lru.Len() > c.size {
+		e := c.lru.Back()
+		c.lru.Remove(e)
+		delete(c.entries, e.Value.(*templateEntry).pc)
+	}
+	return t
+}
+
+// SetTemplateCacheSize 配置内置Logging实现按调用位置缓存的消息模板数量上限，默认为DefaultTemplateCacheSize，
+// 超过上限时淘汰最久未使用的调用位置，小于等于0时不缓存
+func SetTemplateCacheSize(size int) func(*logging) {
+	return func(logging *logging) {
+		logging.templates = newTemplateCache(size)
+	}
+}
+
+// parseTemplate 解析消息模板，占位符格式为{Name}，Name由字母、数字及"_"、"."组成，
+// "{{"、"}}"分别输出"{"、"}"，不合法的占位符按原文输出
+func parseTemplate(template string) *messageTemplate {
+	t := &messageTemplate{raw: template}
+	text := strings.Builder{}
+	for i := 0; i < len(template); i++ {
+		c := template[i]
+		if (c == '{' || c == '}') && i+1 < len(template) && template[i+1] == c {
+			text.WriteByte(c)
+			i++
+			continue
+		}
+		if c == '{' {
+			if end := strings.IndexByte(template[i+1:], '}'); end > 0 && validPlaceholder(template[i+1:i+1+end]) {
+				name := template[i+1 : i+1+end]
+				if text.Len() > 0 {
+					t.tokens = append(t.tokens, templateToken{text: text.String()})
+					text.Reset()
+				}
+				t.tokens = append(t.tokens, templateToken{name: name})
+				t.addName(name)
+				i += end + 1
+				continue
+			}
+		}
+		text.WriteByte(c)
+	}
+	if text.Len() > 0 {
+		t.tokens = append(t.tokens, templateToken{text: text.String()})
+	}
+	return t
+}
+
+func validPlaceholder(name string) bool {
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '.') {
+			return false
+		}
+	}
+	return true
+}
+
+func (t *messageTemplate) addName(name string) {
+	for _, v := range t.names {
+		if v == name {
+			return
+		}
+	}
+	t.names = append(t.names, name)
+}
+
+// LogValue 输出原始模板
+func (t *messageTemplate) LogValue() interface{} {
+	return t.raw
+}
+
+func (t *messageTemplate) String() string {
+	return t.raw
+}
+
+// render 将参数按占位符首次出现的顺序绑定，返回渲染后的日志内容及占位符字段，
+// 缺少参数的占位符按原文输出且不作为字段，多余的参数忽略
+func (t *messageTemplate) render(args []interface{}) (string, []interface{}) {
+	fields := make([]interface{}, 0, 2*len(t.names))
+	for i, name := range t.names {
+		if i >= len(args) {
+			break
+		}
+		fields = append(fields, name, args[i])
+	}
+	buf := strings.Builder{}
+	for _, token := range t.tokens {
+		if token.name == "" {
+			buf.WriteString(token.text)
+			continue
+		}
+		if i := t.index(token.name); i < len(args) {
+			buf.WriteString(util.FormatValue(args[i], false))
+		} else {
+			buf.WriteString("{" + token.name + "}")
+		}
+	}
+	return buf.String(), fields
+}
+
+func (t *messageTemplate) index(name string) int {
+	for i, v := range t.names {
+		if v == name {
+			return i
+		}
+	}
+	return len(t.names)
+}
+
+// templateValue TemplateKey字段的值，输出原始模板，并记录本次日志实际添加的占位符字段的键
+type templateValue struct {
+	*messageTemplate
+	keys []string
+}
+
+// isTemplateField 判断附加信息中的key是否为模板的占位符字段（文本格式时已在日志内容中输出）
+func isTemplateField(keyValues util.KeyValues, key string) bool {
+	t, ok := keyValues.Get(TemplateKey).(*templateValue)
+	if !ok {
+		return false
+	}
+	for _, k := range t.keys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// addTemplateFields 添加占位符字段，返回实际添加的键。占位符与已有附加信息（如WithFields添加的）同名时，
+// 按SetKeyCollision配置的策略处理：默认为占位符字段添加后缀，CollisionPrefix输出时统一添加前缀，此处同样添加后缀，
+// CollisionOverwrite覆盖已有的值，CollisionReject丢弃占位符字段并以ErrKeyCollision调用ErrorHandler
+func (l *logging) addTemplateFields(level Level, keyValues util.KeyValues, fields []interface{}) []string {
+	keys := make([]string, 0, len(fields)/2)
+	exists := func(key string) bool {
+		return hasKey(keyValues, key)
+	}
+	for i := 0; i+1 < len(fields); i += 2 {
+		name := fields[i].(string)
+		key := name
+		if exists(name) {
+			policy, affix := l.keyCollision, l.collisionAffix
+			if policy == CollisionPrefix {
+				policy, affix = CollisionRename, ""
+			}
+			var ok bool
+			if key, ok = policy.ResolveKey(name, affix, exists); !ok {
+				l.handleError(level, fmt.Errorf("%w: %s", ErrKeyCollision, name))
+				continue
+			}
+		}
+		_ = keyValues.Add(key, fields[i+1])
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// LogT 使用消息模板输出日志，占位符渲染到日志内容中，同时作为附加信息字段输出（同名时的处理见addTemplateFields），
+// 原始模板作为TemplateKey字段输出
+func (l *logging) LogT(level Level, depth int, keyValues util.KeyValues, template string, args ...interface{}) {
+	if !l.isEnabled(level, keyValues) {
+		return
+	}
+
+	var pcs [1]uintptr
+	runtime.Callers(depth+2, pcs[:])
+	args, errInfo := extractErrors(resolveArgs(args), false)
+	t := l.templates.get(pcs[0], template)
+	logInfo, fields := t.render(args)
+	if !strings.HasSuffix(logInfo, "\n") {
+		logInfo += "\n"
+	}
+	if keyValues != nil {
+		keyValues = keyValues.Clone()
+	} else {
+		keyValues = util.NewKeyValues()
+	}
+	keys := l.addTemplateFields(level, keyValues, fields)
+	_ = keyValues.Add(TemplateKey, &templateValue{messageTemplate: t, keys: keys})
+	keyValues = withError(keyValues, errInfo)
+	w := l.selectWriter(level)
+	l.format(w, level, depth, keyValues, logInfo)
+
+	if level == PANIC {
+		l.flushBeforeExit()
+		l.panicFunc(l.panicValueFunc(logInfo, keyValues))
+	} else if level <= FATAL {
+		l.processFatal()
+	}
+}
@@ -0,0 +1,10 @@
+module github.com/acmestack/log4go/logrsink
+
+go 1.19
+
+require github.com/acmestack/log4go v0.0.0-20261019002339-dce38929fe44
+
+require github.com/go-logr/logr v1.4.2
+
+// 本地开发时使用仓库中的根模块，发布时去掉replace以使用上面require的版本
+replace github.com/acmestack/log4go => ../
@@ -0,0 +1,115 @@
+/*
+ * Copyright (c) 2022, AcmeStack
+ * All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package logrsink 提供以log4go Logger实现的logr.LogSink，为独立的module，避免log4go依赖logr
+package logrsink
+
+import (
+	"fmt"
+	"github.com/acmestack/log4go/logfactory"
+	"github.com/go-logr/logr"
+)
+
+// logSink 以logfactory.Logger实现的logr.LogSink，V(0)为INFO级别，V(1)及以上为DEBUG级别
+type logSink struct {
+	logger logfactory.Logger
+	// 日志方法到用户调用位置的帧数
+	depth int
+}
+
+// New 获得输出到logger的logr.Logger
+func New(logger logfactory.Logger) logr.Logger {
+	return logr.New(NewLogSink(logger))
+}
+
+// NewLogSink 创建输出到logger的logr.LogSink
+func NewLogSink(logger logfactory.Logger) logr.LogSink {
+	return &logSink{logger: logger, depth: 1}
+}
+
+// Init 根据logr的调用深度调整日志的调用位置
+func (s *logSink) Init(info logr.RuntimeInfo) {
+	s.depth += info.CallDepth
+	s.logger = s.logger.WithDepth(s.depth)
+}
+
+func (s *logSink) Enabled(level int) bool {
+	if level > 0 {
+		return s.logger.DebugEnabled()
+	}
+	return s.logger.InfoEnabled()
+}
+
+func (s *logSink) Info(level int, msg string, keysAndValues ...interface{}) {
+	logger := s.withValues(keysAndValues)
+	if level > 0 {
+		logger.DebugLn(msg)
+	} else {
+		logger.InfoLn(msg)
+	}
+}
+
+func (s *logSink) Error(err error, msg string, keysAndValues ...interface{}) {
+	logger := s.withValues(keysAndValues)
+	if err != nil {
+		logger = logger.WithFields(logfactory.ErrorKey, logfactory.Err(err))
+	}
+	logger.ErrorLn(msg)
+}
+
+func (s *logSink) WithValues(keysAndValues ...interface{}) logr.LogSink {
+	return &logSink{
+		logger: s.withValues(keysAndValues),
+		depth:  s.depth,
+	}
+}
+
+// WithName 附加名称，同logfactory.Logger.WithName以"."分隔
+func (s *logSink) WithName(name string) logr.LogSink {
+	return &logSink{
+		logger: s.logger.WithName(name),
+		depth:  s.depth,
+	}
+}
+
+// WithCallDepth 实现logr.CallDepthLogSink
+func (s *logSink) WithCallDepth(depth int) logr.LogSink {
+	return &logSink{
+		logger: s.logger.WithDepth(s.depth + depth),
+		depth:  s.depth + depth,
+	}
+}
+
+// withValues 附加logr的键值对，键不是string时转换为string，缺少值时值为"(MISSING)"
+func (s *logSink) withValues(keysAndValues []interface{}) logfactory.Logger {
+	if len(keysAndValues) == 0 {
+		return s.logger
+	}
+	kvs := make([]interface{}, 0, len(keysAndValues)+1)
+	for i := 0; i < len(keysAndValues); i += 2 {
+		k, ok := keysAndValues[i].(string)
+		if !ok {
+			k = fmt.Sprint(keysAndValues[i])
+		}
+		var v interface{} = "(MISSING)"
+		if i+1 < len(keysAndValues) {
+			v = keysAndValues[i+1]
+		}
+		kvs = append(kvs, k, v)
+	}
+	return s.logger.WithFields(kvs...)
+}
@@ -0,0 +1,54 @@
+/*
+ * Copyright (c) 2022, AcmeStack
+ * All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package test
+
+import (
+	"errors"
+	"github.com/acmestack/log4go/logfactory"
+	"github.com/acmestack/log4go/logrsink"
+	"github.com/acmestack/log4go/logtest"
+	"strings"
+	"testing"
+)
+
+func TestLogSink(t *testing.T) {
+	rec := logtest.New(t, logfactory.SetLogLevel(logfactory.INFO))
+	logger := logrsink.New(rec.Logger("app")).WithName("db").WithValues("shard", 1)
+
+	logger.Info("connected", "host", "localhost", 42, "odd")
+	logger.V(1).Info("debug skipped")
+	logger.Error(errors.New("timeout"), "query failed", "sql")
+	if logger.V(1).Enabled() || !logger.V(0).Enabled() {
+		t.Fatal("expect V(1) mapped to disabled DEBUG level")
+	}
+
+	entries := rec.Entries()
+	if len(entries) != 2 {
+		t.Fatalf("expect 2 entries, but get %+v", entries)
+	}
+	rec.AssertContains(logfactory.INFO, "shard", 1, "host", "localhost", "42", "odd")
+	rec.AssertContains(logfactory.ERROR, "shard", 1, "sql", "(MISSING)")
+	for _, e := range entries {
+		if e.Name != "app.db" || !strings.HasPrefix(e.Caller, "logrsink_test.go") {
+			t.Fatalf("expect name and caller of logr call site, but get %+v", e)
+		}
+	}
+	if info, ok := entries[1].Fields[logfactory.ErrorKey].(*logfactory.ErrorInfo); !ok || info.Message != "timeout" {
+		t.Fatalf("expect error field, but get %+v", entries[1])
+	}
+}
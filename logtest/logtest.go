@@ -0,0 +1,231 @@
+/*
+ * Copyright (c) 2022, AcmeStack
+ * All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package logtest 提供用于单元测试的Logging，记录结构化的日志并提供断言，日志通过testing.TB.Log输出
+package logtest
+
+import (
+	"fmt"
+	"github.com/acmestack/log4go/logfactory"
+	"github.com/acmestack/log4go/util"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Entry 记录的日志
+type Entry struct {
+	Time    time.Time
+	Level   logfactory.Level
+	Name    string
+	Caller  string
+	Message string
+	// 用户附加的字段，不包括内置的LogTime、LogLevel等
+	Fields map[string]interface{}
+}
+
+// Recorder 记录日志的Formatter，同时持有使用该Formatter的Logging
+type Recorder struct {
+	tb        testing.TB
+	lock      sync.Mutex
+	entries   []Entry
+	exitCodes []int
+	logging   logfactory.Logging
+	formatter util.Formatter
+}
+
+// New 创建Recorder，默认日志级别为DEBUG，Fatal不会退出程序而是记录退出码，opts可覆盖默认配置
+func New(tb testing.TB, opts ...logfactory.LoggingOpt) *Recorder {
+	r := &Recorder{
+		tb:        tb,
+		formatter: &util.TextFormatter{},
+	}
+	defaults := []logfactory.LoggingOpt{
+		logfactory.SetLogLevel(logfactory.DEBUG),
+		logfactory.SetFatalNoTrace(true),
+		logfactory.SetExitFunc(func(code int) {
+			r.lock.Lock()
+			defer r.lock.Unlock()
+			r.exitCodes = append(r.exitCodes, code)
+		}),
+	}
+	r.logging = logfactory.NewLogging(append(defaults, opts...)...)
+	r.logging.SetFormatter(r)
+	r.logging.SetOutput(&tbWriter{tb: tb})
+	return r
+}
+
+// Logging 获得记录日志的Logging
+func (r *Recorder) Logging() logfactory.Logging {
+	return r.logging
+}
+
+// Logger 获得使用记录Logging的Logger，参数同logfactory.GetLogger
+func (r *Recorder) Logger(o ...interface{}) logfactory.Logger {
+	return logfactory.NewFactory(r.logging).GetLogger(o...)
+}
+
+// Install 将fac的Logging替换为记录Logging，测试结束时恢复。
+// 注意logfactory.LoggerFactory只影响之后获得的Logger，ext.NewMutableFactory则同时影响已获得的Logger
+func (r *Recorder) Install(fac logfactory.LoggerFactoryI) {
+	old := fac.GetLogging()
+	fac.Reset(r.logging)
+	r.tb.Cleanup(func() {
+		fac.Reset(old)
+	})
+}
+
+// InstallGlobal 将全局默认的Logging替换为记录Logging，测试结束时恢复
+func (r *Recorder) InstallGlobal() {
+	old := logfactory.DefaultLogging()
+	logfactory.ResetLogging(r.logging)
+	r.tb.Cleanup(func() {
+		logfactory.ResetLogging(old)
+	})
+}
+
+// Format 实现util.Formatter，记录日志并使用util.TextFormatter格式化
+func (r *Recorder) Format(writer io.Writer, keyValues util.KeyValues) error {
+	e := Entry{
+		Name:    util.FormatValue(keyValues.Get(logfactory.NameKey), false),
+		Caller:  util.FormatValue(keyValues.Get(logfactory.CallerKey), false),
+		Message: strings.TrimSuffix(util.FormatValue(keyValues.Get(logfactory.ContentKey), false), "\n"),
+		Fields:  map[string]interface{}{},
+	}
+	e.Level, _ = logfactory.ParseLevel(util.FormatValue(keyValues.Get(logfactory.LevelKey), false))
+	if t, ok := keyValues.Get(logfactory.TimestampKey).(time.Time); ok {
+		e.Time = t
+	}
+	keyValues.Range(func(key string, value interface{}) bool {
+		switch key {
+		case logfactory.TimestampKey, logfactory.LevelKey, logfactory.CallerKey, logfactory.ContentKey, logfactory.NameKey:
+		default:
+			e.Fields[key] = value
+		}
+		return true
+	})
+
+	r.lock.Lock()
+	r.entries = append(r.entries, e)
+	r.lock.Unlock()
+
+	return r.formatter.Format(writer, keyValues)
+}
+
+// Entries 获得所有记录的日志
+func (r *Recorder) Entries() []Entry {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	ret := make([]Entry, len(r.entries))
+	copy(ret, r.entries)
+	return ret
+}
+
+// ExitCodes 获得Fatal日志触发的退出码
+func (r *Recorder) ExitCodes() []int {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	ret := make([]int, len(r.exitCodes))
+	copy(ret, r.exitCodes)
+	return ret
+}
+
+// Reset 清空记录的日志
+func (r *Recorder) Reset() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.entries = nil
+	r.exitCodes = nil
+}
+
+// Find 获得级别为level且包含所有keyAndValues字段的日志，字段值按格式化后的字符串比较
+func (r *Recorder) Find(level logfactory.Level, keyAndValues ...interface{}) []Entry {
+	var ret []Entry
+	for _, e := range r.Entries() {
+		if e.Level == level && e.hasFields(keyAndValues...) {
+			ret = append(ret, e)
+		}
+	}
+	return ret
+}
+
+// AssertContains 断言存在级别为level且包含所有keyAndValues字段的日志
+func (r *Recorder) AssertContains(level logfactory.Level, keyAndValues ...interface{}) {
+	r.tb.Helper()
+	if len(r.Find(level, keyAndValues...)) == 0 {
+		r.tb.Errorf("no %s log with fields %v, logged: %s", logfactory.LogTag[level], keyAndValues, r.summary())
+	}
+}
+
+// AssertMessage 断言存在级别为level且内容包含substr的日志
+func (r *Recorder) AssertMessage(level logfactory.Level, substr string) {
+	r.tb.Helper()
+	for _, e := range r.Find(level) {
+		if strings.Contains(e.Message, substr) {
+			return
+		}
+	}
+	r.tb.Errorf("no %s log contains %q, logged: %s", logfactory.LogTag[level], substr, r.summary())
+}
+
+// AssertNoErrors 断言不存在ERROR及更严重级别的日志
+func (r *Recorder) AssertNoErrors() {
+	r.tb.Helper()
+	for _, e := range r.Entries() {
+		if e.Level <= logfactory.ERROR {
+			r.tb.Errorf("unexpected %s log: %s", logfactory.LogTag[e.Level], e.Message)
+		}
+	}
+}
+
+func (r *Recorder) summary() string {
+	var buf strings.Builder
+	for _, e := range r.Entries() {
+		fmt.Fprintf(&buf, "\n\t[%s] %s %v", logfactory.LogTag[e.Level], e.Message, e.Fields)
+	}
+	return buf.String()
+}
+
+func (e *Entry) hasFields(keyAndValues ...interface{}) bool {
+	for i := 0; i+1 < len(keyAndValues); i += 2 {
+		k, ok := keyAndValues[i].(string)
+		if !ok {
+			return false
+		}
+		v, ok := e.Fields[k]
+		if !ok || util.FormatValue(v, false) != util.FormatValue(keyAndValues[i+1], false) {
+			return false
+		}
+	}
+	return true
+}
+
+// tbWriter 将日志通过testing.TB.Log输出
+type tbWriter struct {
+	tb testing.TB
+}
+
+func (w *tbWriter) Write(d []byte) (int, error) {
+	w.tb.Helper()
+	w.tb.Log(strings.TrimRight(string(d), " \n"))
+	return len(d), nil
+}
@@ -0,0 +1,174 @@
+/*
+ * Copyright (c) 2022, AcmeStack
+ * All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package redact 提供日志脱敏处理，通过logfactory.SetRedactor配置给Logging，
+// 根据附加信息的键名屏蔽整个值，并根据正则表达式屏蔽附加信息值及日志内容中的敏感数据
+package redact
+
+import (
+	"fmt"
+	"github.com/acmestack/log4go/util"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// DefaultMask 默认的屏蔽内容
+const DefaultMask = "[REDACTED]"
+
+// DefaultKeys 默认屏蔽的键名模式
+var DefaultKeys = []string{
+	"*password*", "*passwd*", "*secret*", "*token*", "*api_key*", "*apikey*", "authorization", "cookie", "set-cookie",
+}
+
+// Rule 屏蔽值中敏感数据的规则
+type Rule struct {
+	// Name 规则名称
+	Name string
+	// Pattern 匹配敏感数据的正则表达式
+	Pattern *regexp.Regexp
+	// Validate 进一步校验匹配的内容，返回false时不屏蔽，为nil时屏蔽所有匹配
+	Validate func(match string) bool
+	// Replace 返回替换匹配内容的字符串，为nil时替换为Redactor的Mask
+	Replace func(match string, mask string) string
+}
+
+var (
+	// EmailRule 屏蔽电子邮件地址
+	EmailRule = Rule{
+		Name:    "email",
+		Pattern: regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`),
+	}
+	// CardRule 屏蔽通过Luhn校验的银行卡号（13至19位数字，可以空格或"-"分隔）
+	CardRule = Rule{
+		Name:     "card",
+		Pattern:  regexp.MustCompile(`\b\d(?:[ \-]?\d){12,18}\b`),
+		Validate: Luhn,
+	}
+	// BearerRule 屏蔽Bearer令牌，保留"Bearer "
+	BearerRule = Rule{
+		Name:    "bearer",
+		Pattern: regexp.MustCompile(`(?i)\bbearer\s+[A-Za-z0-9\-._~+/]+=*`),
+		Replace: func(match string, mask string) string {
+			return match[:len("bearer")] + " " + mask
+		},
+	}
+	// DefaultRules 默认的规则
+	DefaultRules = []Rule{EmailRule, CardRule, BearerRule}
+)
+
+// Redactor 实现logfactory.Redactor
+type Redactor struct {
+	// Keys 屏蔽整个值的键名模式，不区分大小写，格式同path.Match，如：*password*
+	Keys []string
+	// Rules 屏蔽值及日志内容中敏感数据的规则
+	Rules []Rule
+	// Mask 屏蔽内容，为空时使用DefaultMask
+	Mask string
+}
+
+// New 创建使用默认键名模式及规则的Redactor
+func New() *Redactor {
+	return &Redactor{
+		Keys:  DefaultKeys,
+		Rules: DefaultRules,
+		Mask:  DefaultMask,
+	}
+}
+
+func (r *Redactor) mask() string {
+	if r.Mask == "" {
+		return DefaultMask
+	}
+	return r.Mask
+}
+
+// MatchKey 判断键名是否需要屏蔽整个值，分组的键（如http.authorization）同时匹配最后一段
+func (r *Redactor) MatchKey(key string) bool {
+	key = strings.ToLower(key)
+	name := key
+	if idx := strings.LastIndex(key, util.GroupSeparator); idx >= 0 {
+		name = key[idx+len(util.GroupSeparator):]
+	}
+	for _, p := range r.Keys {
+		p = strings.ToLower(p)
+		if ok, _ := path.Match(p, key); ok {
+			return true
+		}
+		if name != key {
+			if ok, _ := path.Match(p, name); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// RedactField 键名匹配时返回Mask，值为string、error或fmt.Stringer时按规则屏蔽，有屏蔽时返回string
+func (r *Redactor) RedactField(key string, value interface{}) interface{} {
+	if r.MatchKey(key) {
+		return r.mask()
+	}
+	switch v := value.(type) {
+	case string:
+		return r.RedactMessage(v)
+	case error, fmt.Stringer:
+		s := fmt.Sprint(v)
+		if ret := r.RedactMessage(s); ret != s {
+			return ret
+		}
+	}
+	return value
+}
+
+// RedactMessage 按规则屏蔽内容中的敏感数据
+func (r *Redactor) RedactMessage(message string) string {
+	for i := range r.Rules {
+		rule := &r.Rules[i]
+		message = rule.Pattern.ReplaceAllStringFunc(message, func(match string) string {
+			if rule.Validate != nil && !rule.Validate(match) {
+				return match
+			}
+			if rule.Replace != nil {
+				return rule.Replace(match, r.mask())
+			}
+			return r.mask()
+		})
+	}
+	return message
+}
+
+// Luhn 对s中的数字进行Luhn校验（忽略非数字字符）
+func Luhn(s string) bool {
+	sum, n := 0, 0
+	for i := len(s) - 1; i >= 0; i-- {
+		c := s[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		d := int(c - '0')
+		if n%2 == 1 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		n++
+	}
+	return n > 0 && sum%10 == 0
+}
@@ -0,0 +1,162 @@
+/*
+ * Copyright (c) 2022, AcmeStack
+ * All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package stdlog 提供标准库log包及Printf风格接口到log4go Logger的适配
+package stdlog
+
+import (
+	"fmt"
+	"github.com/acmestack/log4go/logfactory"
+	"log"
+	"strings"
+	"sync"
+)
+
+// stdDepth logAt到用户调用位置的帧数：logAt <- Write <- (*log.Logger).output <- log.Printf等
+const stdDepth = 4
+
+// Writer 将*log.Logger的输出转换为log4go日志的io.Writer，每次Write为一条日志，
+// 会去掉*log.Logger按Flags及Prefix添加的前缀（日期、时间、文件位置）
+type Writer struct {
+	logger logfactory.Logger
+	level  logfactory.Level
+	flags  int
+	prefix string
+}
+
+// NewWriter 创建Writer
+// Param: logger - 输出日志的Logger，level - 日志级别，flags、prefix - 写入该Writer的*log.Logger的Flags及Prefix，用于去掉前缀
+func NewWriter(logger logfactory.Logger, level logfactory.Level, flags int, prefix string) *Writer {
+	return &Writer{
+		logger: logger.WithDepth(stdDepth),
+		level:  level,
+		flags:  flags,
+		prefix: prefix,
+	}
+}
+
+func (w *Writer) Write(p []byte) (int, error) {
+	msg := strings.TrimSuffix(trimStdPrefix(string(p), w.flags, w.prefix), "\n")
+	logAt(w.logger, w.level, msg)
+	return len(p), nil
+}
+
+// trimStdPrefix 去掉*log.Logger按flags及prefix添加的前缀
+func trimStdPrefix(s string, flags int, prefix string) string {
+	if flags&log.Lmsgprefix == 0 {
+		s = strings.TrimPrefix(s, prefix)
+	}
+	if flags&(log.Ldate|log.Ltime|log.Lmicroseconds) != 0 {
+		if flags&log.Ldate != 0 {
+			s = skipField(s)
+		}
+		if flags&(log.Ltime|log.Lmicroseconds) != 0 {
+			s = skipField(s)
+		}
+	}
+	if flags&(log.Lshortfile|log.Llongfile) != 0 {
+		if i := strings.Index(s, ": "); i >= 0 {
+			s = s[i+2:]
+		}
+	}
+	if flags&log.Lmsgprefix != 0 {
+		s = strings.TrimPrefix(s, prefix)
+	}
+	return s
+}
+
+// skipField 去掉以空格结尾的第一个字段
+func skipField(s string) string {
+	if i := strings.IndexByte(s, ' '); i >= 0 {
+		return s[i+1:]
+	}
+	return s
+}
+
+// NewStdLogger 创建输出到logger的*log.Logger，用于只接受*log.Logger的第三方库
+func NewStdLogger(logger logfactory.Logger, level logfactory.Level) *log.Logger {
+	return log.New(NewWriter(logger, level, 0, ""), "", 0)
+}
+
+var redirectLock sync.Mutex
+
+// RedirectStdLog 将标准库log包的默认Logger输出重定向到logger，去掉标准库添加的日期、时间前缀（由log4go输出）
+// Return: 恢复原输出、Flags及Prefix的函数
+func RedirectStdLog(logger logfactory.Logger, level logfactory.Level) (restore func()) {
+	redirectLock.Lock()
+	defer redirectLock.Unlock()
+
+	out, flags, prefix := log.Writer(), log.Flags(), log.Prefix()
+	log.SetFlags(flags &^ (log.Ldate | log.Ltime | log.Lmicroseconds | log.LUTC))
+	log.SetOutput(NewWriter(logger, level, log.Flags(), prefix))
+	return func() {
+		redirectLock.Lock()
+		defer redirectLock.Unlock()
+		log.SetOutput(out)
+		log.SetFlags(flags)
+		log.SetPrefix(prefix)
+	}
+}
+
+// Printer 最小的Printf风格日志接口
+type Printer interface {
+	Printf(format string, args ...interface{})
+}
+
+// PrintLogger 以固定级别输出到Logger的Printer，同时实现Print、Println
+type PrintLogger struct {
+	logger logfactory.Logger
+	level  logfactory.Level
+}
+
+// NewPrinter 创建以level输出到logger的PrintLogger
+func NewPrinter(logger logfactory.Logger, level logfactory.Level) *PrintLogger {
+	return &PrintLogger{
+		// logAt <- Printf等
+		logger: logger.WithDepth(2),
+		level:  level,
+	}
+}
+
+func (p *PrintLogger) Printf(format string, args ...interface{}) {
+	logAt(p.logger, p.level, strings.TrimSuffix(fmt.Sprintf(format, args...), "\n"))
+}
+
+func (p *PrintLogger) Print(args ...interface{}) {
+	logAt(p.logger, p.level, strings.TrimSuffix(fmt.Sprint(args...), "\n"))
+}
+
+func (p *PrintLogger) Println(args ...interface{}) {
+	logAt(p.logger, p.level, args...)
+}
+
+func logAt(logger logfactory.Logger, level logfactory.Level, args ...interface{}) {
+	switch level {
+	case logfactory.DEBUG:
+		logger.DebugLn(args...)
+	case logfactory.INFO:
+		logger.InfoLn(args...)
+	case logfactory.WARN:
+		logger.WarnLn(args...)
+	case logfactory.ERROR:
+		logger.ErrorLn(args...)
+	case logfactory.PANIC:
+		logger.PanicLn(args...)
+	default:
+		logger.FatalLn(args...)
+	}
+}
@@ -0,0 +1,123 @@
+/*
+ * Copyright (c) 2022, AcmeStack
+ * All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/acmestack/log4go/admin"
+	"github.com/acmestack/log4go/logfactory"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func serveAdmin(t *testing.T, h http.Handler, method, target, body string) admin.Levels {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(method, target, strings.NewReader(body)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("%s %s %s: expect 200, but get %d %s", method, target, body, rec.Code, rec.Body.String())
+	}
+	ret := admin.Levels{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &ret); err != nil {
+		t.Fatal(err)
+	}
+	return ret
+}
+
+func TestAdminLevels(t *testing.T) {
+	buf := &bytes.Buffer{}
+	factory := logfactory.NewFactory(logfactory.NewLogging(logfactory.SetColorFlag(logfactory.DisableColor)))
+	factory.GetLogging().SetOutput(buf)
+	h := admin.NewHandler(factory)
+	defer h.Stop()
+
+	levels := serveAdmin(t, h, http.MethodGet, "/", "")
+	if levels.Root.Level != "INFO" || len(levels.Loggers) != 0 {
+		t.Fatalf("unexpected levels %+v", levels)
+	}
+
+	levels = serveAdmin(t, h, http.MethodPut, "/", `{"name":"db","level":"debug"}`)
+	if len(levels.Loggers) != 1 || levels.Loggers[0].Name != "db" || levels.Loggers[0].Level != "DEBUG" {
+		t.Fatalf("unexpected levels %+v", levels)
+	}
+	db := factory.GetLogger("db").WithName("pool")
+	db.Debug("db debug")
+	factory.GetLogger("dbx").Debug("dbx debug")
+	factory.GetLogger().Debug("root debug")
+	if !db.DebugEnabled() || !strings.Contains(buf.String(), "db debug") ||
+		strings.Contains(buf.String(), "dbx debug") || strings.Contains(buf.String(), "root debug") {
+		t.Fatalf("expect only db prefix at debug, but get %q", buf.String())
+	}
+
+	levels = serveAdmin(t, h, http.MethodDelete, "/?name=db", "")
+	if len(levels.Loggers) != 0 || db.DebugEnabled() {
+		t.Fatalf("expect db level removed, but get %+v", levels)
+	}
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/", strings.NewReader(`{"level":"verbose"}`)))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expect bad request for unknown level, but get %d", rec.Code)
+	}
+}
+
+func TestAdminLevelTTL(t *testing.T) {
+	factory := logfactory.NewFactory(logfactory.NewLogging())
+	h := admin.NewHandler(factory)
+	defer h.Stop()
+
+	levels := serveAdmin(t, h, http.MethodPut, "/", `{"level":"debug","ttl":"50ms"}`)
+	if levels.Root.Level != "DEBUG" || levels.Root.ExpiresAt == nil {
+		t.Fatalf("unexpected levels %+v", levels)
+	}
+	serveAdmin(t, h, http.MethodPut, "/", `{"name":"db","level":"error","ttl":"50ms"}`)
+	serveAdmin(t, h, http.MethodPut, "/", `{"name":"db","level":"warn","ttl":"50ms"}`)
+
+	time.Sleep(150 * time.Millisecond)
+	levels = serveAdmin(t, h, http.MethodGet, "/", "")
+	if levels.Root.Level != "INFO" || levels.Root.ExpiresAt != nil || len(levels.Loggers) != 0 {
+		t.Fatalf("expect levels reverted, but get %+v", levels)
+	}
+}
+
+func TestAdminLevelTTLTwice(t *testing.T) {
+	factory := logfactory.NewFactory(logfactory.NewLogging())
+	h := admin.NewHandler(factory)
+	defer h.Stop()
+
+	for i := 0; i < 100; i++ {
+		// 第一次修改的timer可能在第二次修改时已触发，不能恢复第二次的修改
+		if err := h.SetLevel(admin.LevelRequest{Name: "db", Level: "debug", TTL: "1ms"}); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(time.Millisecond)
+		if err := h.SetLevel(admin.LevelRequest{Name: "db", Level: "warn", TTL: "1h"}); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(time.Millisecond)
+		levels := h.Levels()
+		if len(levels.Loggers) != 1 || levels.Loggers[0].Level != "WARN" || levels.Loggers[0].ExpiresAt == nil {
+			t.Fatalf("expect second ttl kept, but get %+v", levels)
+		}
+		h.RemoveLevel("db")
+	}
+}
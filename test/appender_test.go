@@ -0,0 +1,117 @@
+/*
+ * Copyright (c) 2022, AcmeStack
+ * All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package test
+
+import (
+	"bytes"
+	"github.com/acmestack/log4go/appender"
+	"github.com/acmestack/log4go/logfactory"
+	"github.com/acmestack/log4go/util"
+	"github.com/acmestack/log4go/writer"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMemoryAppenderQuery(t *testing.T) {
+	buf := &bytes.Buffer{}
+	mem := appender.NewMemoryAppender(2, buf)
+	logging := logfactory.NewLogging(logfactory.SetLogLevel(logfactory.DEBUG))
+	logging.SetOutput(mem)
+	fac := logfactory.NewFactory(logging)
+
+	start := time.Now()
+	fac.GetLogger("a.b").Debug("debug1")
+	fac.GetLogger("a.b").Debug("debug2")
+	fac.GetLogger("a.b").Debug("debug3")
+	fac.GetLogger("c").WithFields("RequestID", 1).Info("info1")
+	fac.GetLogger("c").WithFields("RequestID", 2).Error("error1")
+
+	records := mem.Records(appender.Query{Levels: []logfactory.Level{logfactory.DEBUG}})
+	if len(records) != 2 || records[0].Content != "debug2" || records[1].Content != "debug3" {
+		t.Fatalf("expect last 2 debug records, but get %+v", records)
+	}
+	if len(mem.Records(appender.Query{Name: "a"})) != 2 {
+		t.Fatal("expect query by name prefix")
+	}
+	records = mem.Records(appender.Query{Fields: map[string]interface{}{"RequestID": 2}})
+	if len(records) != 1 || records[0].Level != logfactory.ERROR {
+		t.Fatalf("expect query by field, but get %+v", records)
+	}
+	if len(mem.Records(appender.Query{Since: start})) != 4 || len(mem.Records(appender.Query{Until: start})) != 0 {
+		t.Fatal("expect query by time range")
+	}
+
+	dump := &bytes.Buffer{}
+	_ = mem.Dump(dump, appender.Query{Levels: []logfactory.Level{logfactory.INFO}})
+	if !strings.Contains(dump.String(), "info1") || strings.Contains(dump.String(), "error1") {
+		t.Fatalf("unexpected dump %q", dump.String())
+	}
+
+	// 名称前缀按"."分隔的段匹配
+	fac.GetLogger("a.bx").Info("other")
+	if len(mem.Records(appender.Query{Name: "a.b"})) != 2 || len(mem.Records(appender.Query{Name: "a"})) != 3 {
+		t.Fatal("expect query by dotted name segments")
+	}
+}
+
+func TestMemoryAppenderFlushOnError(t *testing.T) {
+	buf := &bytes.Buffer{}
+	mem := appender.NewMemoryAppender(10, buf, appender.FlushOnError("RequestID"))
+	logging := logfactory.NewLogging(logfactory.SetLogLevel(logfactory.DEBUG))
+	logging.SetFormatter(&util.JsonFormatter{})
+	logging.SetOutput(mem)
+	logger := logfactory.NewFactory(logging).GetLogger()
+
+	logger.WithFields("RequestID", "r1").Debug("r1 debug")
+	logger.WithFields("RequestID", "r2").Debug("r2 debug")
+	logger.WithFields("RequestID", "r1").Info("r1 info")
+	if strings.Contains(buf.String(), "debug") || !strings.Contains(buf.String(), "r1 info") {
+		t.Fatalf("expect debug records buffered, but get %q", buf.String())
+	}
+
+	logger.WithFields("RequestID", "r1").Error("r1 error")
+	out := buf.String()
+	if !strings.Contains(out, "r1 debug") || strings.Contains(out, "r2 debug") ||
+		strings.Index(out, "r1 debug") > strings.Index(out, "r1 error") {
+		t.Fatalf("expect r1 debug records flushed before error, but get %q", out)
+	}
+}
+
+func TestMemoryAppenderKeepLevel(t *testing.T) {
+	debug := &bytes.Buffer{}
+	errs := &bytes.Buffer{}
+	mem := appender.NewMemoryAppender(10, writer.NewLevelRouter(nil).
+		Route(logfactory.DEBUG, debug).
+		Route(logfactory.ERROR, errs), appender.FlushOnError(""))
+	logging := logfactory.NewLogging(logfactory.SetLogLevel(logfactory.DEBUG))
+	logging.SetOutput(mem)
+	logger := logfactory.NewFactory(logging).GetLogger("svc")
+
+	logger.DebugLn("buffered debug")
+	if debug.Len() != 0 || len(mem.Records(appender.Query{})) != 1 {
+		t.Fatalf("expect debug record kept without formatter, but get %q", debug.String())
+	}
+	logger.ErrorLn("failed")
+	if !strings.Contains(debug.String(), "buffered debug") || strings.Contains(errs.String(), "buffered debug") {
+		t.Fatalf("expect debug record flushed to DEBUG writer, debug: %q error: %q", debug.String(), errs.String())
+	}
+	if !strings.Contains(errs.String(), "failed") {
+		t.Fatalf("expect error record in ERROR writer, but get %q", errs.String())
+	}
+}
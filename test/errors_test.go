@@ -0,0 +1,141 @@
+/*
+ * Copyright (c) 2022, AcmeStack
+ * All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/acmestack/log4go/logfactory"
+	"github.com/acmestack/log4go/util"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+type multiError []error
+
+func (e multiError) Error() string {
+	return fmt.Sprint([]error(e))
+}
+
+func (e multiError) Unwrap() []error {
+	return e
+}
+
+type frame uintptr
+
+type stackTrace []frame
+
+type tracedError struct {
+	msg string
+	pcs []uintptr
+}
+
+func newTracedError(msg string) *tracedError {
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(2, pcs)
+	return &tracedError{msg: msg, pcs: pcs[:n]}
+}
+
+func (e *tracedError) Error() string {
+	return e.msg
+}
+
+func (e *tracedError) StackTrace() stackTrace {
+	ret := make(stackTrace, len(e.pcs))
+	for i, v := range e.pcs {
+		ret[i] = frame(v)
+	}
+	return ret
+}
+
+func TestErrChain(t *testing.T) {
+	base := errors.New("disk full")
+	err := fmt.Errorf("save: %w", multiError{base, newTracedError("traced")})
+
+	info := logfactory.Err(err)
+	if info.Message != "save: [disk full traced]" || info.Type != "*fmt.wrapError" {
+		t.Fatalf("unexpected error info %+v", info)
+	}
+	if len(info.Causes) != 1 || len(info.Causes[0].Causes) != 2 {
+		t.Fatalf("expect unwrap and join chain, but get %s", info)
+	}
+	if s := info.StackTrace(); len(s) == 0 || !strings.HasSuffix(s[0].Function, "test.TestErrChain") {
+		t.Fatalf("expect stack from traced error, but get %v", s)
+	}
+	if info.String() != "save: [disk full traced] [*fmt.wrapError <- test.multiError <- (*errors.errorString, *test.tracedError)]" {
+		t.Fatalf("unexpected text %s", info)
+	}
+	if logfactory.Err(nil) != nil {
+		t.Fatal("expect nil for nil error")
+	}
+}
+
+func TestLogErrField(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logging := logfactory.NewLogging()
+	logging.SetFormatter(&util.JsonFormatter{})
+	logging.SetOutput(buf)
+	logger := logfactory.NewFactory(logging).GetLogger()
+
+	check := func(content string) {
+		t.Helper()
+		var v struct {
+			LogContent string
+			LogError   *logfactory.ErrorInfo
+		}
+		if err := json.Unmarshal(buf.Bytes(), &v); err != nil {
+			t.Fatalf("unmarshal %q failed: %v", buf.String(), err)
+		}
+		if v.LogContent != content || v.LogError == nil || v.LogError.Message != "disk full" {
+			t.Fatalf("expect error field, but get %s", buf.String())
+		}
+		buf.Reset()
+	}
+
+	err := errors.New("disk full")
+	logger.Error("save failed: ", logfactory.Err(err))
+	check("save failed: disk full")
+	logger.ErrorF("save %s failed: %w", "a.txt", err)
+	check("save a.txt failed: disk full\n")
+	logger.ErrorF("save: %w", logfactory.Err(err))
+	check("save: disk full\n")
+	logger.ErrorF("save: %v", logfactory.Err(err))
+	check("save: disk full\n")
+
+	logger.Info("no error")
+	if strings.Contains(buf.String(), logfactory.ErrorKey) {
+		t.Fatalf("expect error field not kept in logger, but get %s", buf.String())
+	}
+}
+
+func TestLogErrText(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logging := logfactory.NewLogging(logfactory.SetColorFlag(logfactory.DisableColor))
+	logging.SetOutput(buf)
+	logger := logfactory.NewFactory(logging).GetLogger()
+
+	logger.Error("failed: ", logfactory.Err(newTracedError("traced")))
+	lines := strings.Split(buf.String(), "\n")
+	if len(lines) < 3 || !strings.HasSuffix(lines[0], "traced [*test.tracedError] failed: traced") ||
+		!strings.HasSuffix(lines[1], "test.TestLogErrText") {
+		t.Fatalf("unexpected text output %q", buf.String())
+	}
+}
@@ -0,0 +1,163 @@
+/*
+ * Copyright (c) 2022, AcmeStack
+ * All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/acmestack/log4go/logfactory"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type hookCalls struct {
+	lock  sync.Mutex
+	calls []string
+}
+
+func (c *hookCalls) add(name string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.calls = append(c.calls, name)
+}
+
+func (c *hookCalls) String() string {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return strings.Join(c.calls, ",")
+}
+
+func TestFatalShutdownHooks(t *testing.T) {
+	calls := &hookCalls{}
+	defer logfactory.AddShutdownHook("first", func(ctx context.Context) error {
+		calls.add("first")
+		return errors.New("close db failed")
+	})()
+	defer logfactory.AddShutdownHook("slow", func(ctx context.Context) error {
+		calls.add("slow")
+		time.Sleep(time.Second)
+		return nil
+	})()
+	removed := logfactory.AddShutdownHook("removed", func(ctx context.Context) error {
+		calls.add("removed")
+		return nil
+	})
+	removed()
+
+	var errs []error
+	exitCode := 0
+	buf := &bytes.Buffer{}
+	logging := logfactory.NewLogging(
+		logfactory.SetFatalNoTrace(true),
+		logfactory.SetExitCode(3),
+		logfactory.SetShutdownTimeout(50*time.Millisecond),
+		logfactory.SetErrorHandler(func(level logfactory.Level, err error) {
+			errs = append(errs, err)
+		}),
+		logfactory.SetExitFunc(func(code int) {
+			exitCode = code
+		}))
+	logging.SetOutput(buf)
+
+	start := time.Now()
+	logfactory.NewFactory(logging).GetLogger().Fatal("fatal test")
+	if time.Since(start) > 500*time.Millisecond {
+		t.Fatalf("expect slow hook timeout, but take %v", time.Since(start))
+	}
+	if exitCode != 3 || calls.String() != "first,slow" {
+		t.Fatalf("expect hooks run in order then exit, code: %d calls: %v", exitCode, calls)
+	}
+	if len(errs) != 2 || !strings.Contains(errs[0].Error(), "close db failed") ||
+		!errors.Is(errs[1], context.DeadlineExceeded) {
+		t.Fatalf("expect hook errors reported, but get %v", errs)
+	}
+	if !strings.Contains(buf.String(), "fatal test") {
+		t.Fatalf("expect fatal log, but get %q", buf.String())
+	}
+}
+
+func TestFatalAsError(t *testing.T) {
+	hookCalled := false
+	defer logfactory.AddShutdownHook("hook", func(ctx context.Context) error {
+		hookCalled = true
+		return nil
+	})()
+
+	exited := false
+	buf := &bytes.Buffer{}
+	logging := logfactory.NewLogging(
+		logfactory.SetFatalNoTrace(true),
+		logfactory.SetFatalMode(logfactory.FatalAsError),
+		logfactory.SetExitFunc(func(code int) {
+			exited = true
+		}))
+	logging.SetOutput(buf)
+
+	logfactory.NewFactory(logging).GetLogger().FatalF("fatal %s", "test")
+	if exited || hookCalled || !strings.Contains(buf.String(), "fatal test") {
+		t.Fatalf("expect fatal logged only, exited: %v hook: %v output: %q", exited, hookCalled, buf.String())
+	}
+}
+
+func TestFatalConcurrent(t *testing.T) {
+	calls := &hookCalls{}
+	var logger logfactory.Logger
+	hookDone := make(chan struct{})
+	defer logfactory.AddShutdownHook("slow", func(ctx context.Context) error {
+		calls.add("slow")
+		// 钩子中再次输出Fatal日志不会重复执行钩子，也不会等待自身
+		logger.Fatal("fatal in hook")
+		time.Sleep(100 * time.Millisecond)
+		close(hookDone)
+		return nil
+	})()
+
+	exits := &hookCalls{}
+	logging := logfactory.NewLogging(
+		logfactory.SetFatalNoTrace(true),
+		logfactory.SetExitFunc(func(code int) {
+			select {
+			case <-hookDone:
+				exits.add("done")
+			default:
+				exits.add("running")
+			}
+		}))
+	logging.SetOutput(io.Discard)
+	logger = logfactory.NewFactory(logging).GetLogger()
+
+	wait := sync.WaitGroup{}
+	for i := 0; i < 2; i++ {
+		wait.Add(1)
+		go func() {
+			defer wait.Done()
+			logger.Fatal("fatal test")
+		}()
+	}
+	wait.Wait()
+	if calls.String() != "slow" {
+		t.Fatalf("expect hook run once, but get %v", calls)
+	}
+	if exits.String() != "running,done,done" {
+		t.Fatalf("expect exit from hook first and others wait for hooks, but get %v", exits)
+	}
+}
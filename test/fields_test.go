@@ -0,0 +1,174 @@
+/*
+ * Copyright (c) 2022, AcmeStack
+ * All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"github.com/acmestack/log4go/ext"
+	"github.com/acmestack/log4go/logfactory"
+	"github.com/acmestack/log4go/redact"
+	"github.com/acmestack/log4go/util"
+	"reflect"
+	"testing"
+)
+
+func logJson(t *testing.T, logger func(logfactory.LoggerFactoryI) logfactory.Logger, nested bool, opts ...logfactory.LoggingOpt) map[string]interface{} {
+	buf := &bytes.Buffer{}
+	logging := logfactory.NewLogging(opts...)
+	logging.SetFormatter(&util.JsonFormatter{Nested: nested})
+	logging.SetOutput(buf)
+	logger(logfactory.NewFactory(logging)).Info("test")
+
+	var v map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &v); err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func TestKeyCollision(t *testing.T) {
+	clobber := func(fac logfactory.LoggerFactoryI) logfactory.Logger {
+		return fac.GetLogger().WithFields(logfactory.LevelKey, "user", logfactory.LevelKey+"_", "exist", "other", 1)
+	}
+
+	v := logJson(t, clobber, false)
+	if v[logfactory.LevelKey] != "INFO" || v[logfactory.LevelKey+"__"] != "user" || v[logfactory.LevelKey+"_"] != "exist" {
+		t.Fatalf("expect renamed field by default, but get %v", v)
+	}
+
+	v = logJson(t, clobber, false, logfactory.SetKeyCollision(logfactory.CollisionPrefix, ""))
+	prefix := logfactory.DefaultCollisionPrefix
+	if v[logfactory.LevelKey] != "INFO" || v[prefix+logfactory.LevelKey] != "user" ||
+		v[prefix+logfactory.LevelKey+"_"] != "exist" || v[prefix+"other"] != float64(1) || v["other"] != nil {
+		t.Fatalf("expect all fields prefixed, but get %v", v)
+	}
+
+	v = logJson(t, clobber, false, logfactory.SetKeyCollision(logfactory.CollisionRename, "_user"))
+	if v[logfactory.LevelKey] != "INFO" || v[logfactory.LevelKey+"_user"] != "user" {
+		t.Fatalf("expect renamed field with custom suffix, but get %v", v)
+	}
+
+	var errs []error
+	v = logJson(t, clobber, false,
+		logfactory.SetKeyCollision(logfactory.CollisionReject, ""),
+		logfactory.SetErrorHandler(func(level logfactory.Level, err error) {
+			errs = append(errs, err)
+		}))
+	if v[logfactory.LevelKey] != "INFO" || v["other"] != float64(1) || len(v) != 6 {
+		t.Fatalf("expect rejected field dropped, but get %v", v)
+	}
+	if len(errs) != 1 || !errors.Is(errs[0], logfactory.ErrKeyCollision) {
+		t.Fatalf("expect ErrKeyCollision, but get %v", errs)
+	}
+
+	v = logJson(t, clobber, false, logfactory.SetKeyCollision(logfactory.CollisionOverwrite, ""))
+	if v[logfactory.LevelKey] != "user" {
+		t.Fatalf("expect overwritten level, but get %v", v)
+	}
+}
+
+func TestWithGroup(t *testing.T) {
+	for _, fac := range []logfactory.LoggerFactoryI{nil, ext.NewMutableFactory(logfactory.NewLogging())} {
+		grouped := func(f logfactory.LoggerFactoryI) logfactory.Logger {
+			if fac != nil {
+				fac.Reset(f.GetLogging())
+				f = fac
+			}
+			return f.GetLogger("svc").WithFields("id", 1).
+				WithGroup("http").WithFields("status", 200, logfactory.ForceLevelKey, logfactory.INFO).
+				WithGroup("req").WithDepth(0).WithFields("method", "GET").
+				WithGroup("")
+		}
+
+		v := logJson(t, grouped, false)
+		expect := map[string]interface{}{
+			"id": float64(1), "http.status": float64(200), "http.req.method": "GET", logfactory.NameKey: "svc",
+		}
+		for k, e := range expect {
+			if v[k] != e {
+				t.Fatalf("expect %s: %v, but get %v", k, e, v)
+			}
+		}
+		if _, ok := v["http."+logfactory.ForceLevelKey]; ok {
+			t.Fatalf("internal key must not be grouped: %v", v)
+		}
+
+		v = logJson(t, grouped, true)
+		http := map[string]interface{}{
+			"status": float64(200),
+			"req":    map[string]interface{}{"method": "GET"},
+		}
+		if !reflect.DeepEqual(v["http"], http) || v["id"] != float64(1) {
+			t.Fatalf("expect nested json, but get %v", v)
+		}
+	}
+}
+
+func TestNestedJsonConflict(t *testing.T) {
+	buf := &bytes.Buffer{}
+	f := &util.JsonFormatter{Nested: true}
+	if err := f.Format(buf, util.NewKeyValues("a.b", 1, "a", 2, "c.d", 3)); err != nil {
+		t.Fatal(err)
+	}
+	var v map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &v); err != nil {
+		t.Fatal(err)
+	}
+	expect := map[string]interface{}{"a": float64(2), "a.b": float64(1), "c": map[string]interface{}{"d": float64(3)}}
+	if !reflect.DeepEqual(v, expect) {
+		t.Fatalf("expect %v, but get %v", expect, v)
+	}
+}
+
+func TestRedactGroupedKey(t *testing.T) {
+	r := redact.New()
+	if !r.MatchKey("http.Authorization") || r.MatchKey("authorization.id") {
+		t.Fatal("expect match last segment of grouped key")
+	}
+}
+
+func TestMergeKeyValuesCollision(t *testing.T) {
+	merge := func(policy util.KeyCollision) (util.KeyValues, error) {
+		base := util.NewKeyValues(logfactory.LevelKey, "INFO", logfactory.LevelKey+"_", "exist")
+		return util.MergeKeyValuesWithCollision(policy, "", base, util.NewKeyValues(logfactory.LevelKey, "user", "other", 1))
+	}
+
+	kvs, err := util.MergeKeyValues(util.NewKeyValues(logfactory.LevelKey, "INFO"), util.NewKeyValues(logfactory.LevelKey, "user"))
+	if err != nil || kvs.Get(logfactory.LevelKey) != "INFO" || kvs.Get(logfactory.LevelKey+"_") != "user" {
+		t.Fatalf("expect renamed by default, but get %v %v", kvs.GetAll(), err)
+	}
+	kvs, err = merge(util.CollisionRename)
+	if err != nil || kvs.Get(logfactory.LevelKey) != "INFO" || kvs.Get(logfactory.LevelKey+"__") != "user" || kvs.Get("other") != 1 {
+		t.Fatalf("expect renamed key, but get %v %v", kvs.GetAll(), err)
+	}
+	kvs, err = merge(util.CollisionPrefix)
+	if err != nil || kvs.Get(logfactory.LevelKey) != "INFO" || kvs.Get(util.DefaultCollisionPrefix+logfactory.LevelKey) != "user" ||
+		kvs.Get(util.DefaultCollisionPrefix+"other") != 1 || kvs.Get("other") != nil {
+		t.Fatalf("expect prefixed keys, but get %v %v", kvs.GetAll(), err)
+	}
+	kvs, err = merge(util.CollisionOverwrite)
+	if err != nil || kvs.Get(logfactory.LevelKey) != "user" {
+		t.Fatalf("expect overwritten key, but get %v %v", kvs.GetAll(), err)
+	}
+	kvs, err = merge(util.CollisionReject)
+	if !errors.Is(err, util.ErrKeyCollision) || kvs.Get(logfactory.LevelKey) != "INFO" || kvs.Get("other") != 1 {
+		t.Fatalf("expect rejected key, but get %v %v", kvs.GetAll(), err)
+	}
+}
@@ -0,0 +1,168 @@
+/*
+ * Copyright (c) 2022, AcmeStack
+ * All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package test
+
+import (
+	"bytes"
+	"context"
+	"github.com/acmestack/log4go/ext"
+	"github.com/acmestack/log4go/httplog"
+	"github.com/acmestack/log4go/logfactory"
+	"github.com/acmestack/log4go/logtest"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestForceLevelContext(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logging := logfactory.NewLogging(logfactory.SetColorFlag(logfactory.DisableColor))
+	logging.SetOutput(buf)
+	loggers := map[string]logfactory.Logger{
+		"default": logfactory.NewFactory(logging).GetLogger("svc"),
+		"mutable": ext.NewMutableFactory(logging).GetLogger("svc"),
+	}
+	for name, logger := range loggers {
+		t.Run(name, func(t *testing.T) {
+			buf.Reset()
+			logger.Debug("plain debug")
+			ctxLogger := logger.WithContext(logfactory.WithForceLevel(context.Background(), logfactory.DEBUG))
+			if logger.DebugEnabled() || !ctxLogger.DebugEnabled() {
+				t.Fatal("expect debug enabled for forced logger only")
+			}
+			ctxLogger.DebugLn("forced debug")
+			logger.WithFields(logfactory.ForceLevelKey, logfactory.DEBUG).DebugLn("fields debug")
+			out := buf.String()
+			if strings.Contains(out, "plain debug") || !strings.Contains(out, "forced debug") ||
+				!strings.Contains(out, "fields debug") || strings.Count(out, "\n") != 2 {
+				t.Fatalf("unexpected output %q", out)
+			}
+		})
+	}
+}
+
+func TestForceLevelMiddleware(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logging := logfactory.NewLogging(logfactory.SetColorFlag(logfactory.DisableColor))
+	logging.SetOutput(buf)
+	logger := logfactory.NewFactory(logging).GetLogger()
+
+	h := httplog.ForceLevel("", func(r *http.Request) bool {
+		return r.Header.Get("X-Token") == "secret"
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		logger.WithContext(r.Context()).DebugLn("handle", r.URL.Path)
+	}))
+
+	for _, path := range []string{"/allowed", "/denied", "/none"} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if path != "/none" {
+			req.Header.Set(httplog.DefaultForceLevelHeader, "debug")
+		}
+		if path == "/allowed" {
+			req.Header.Set("X-Token", "secret")
+		}
+		h.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	if out := buf.String(); !strings.Contains(out, "handle /allowed") || strings.Count(out, "\n") != 1 {
+		t.Fatalf("expect debug log for allowed request only, but get %q", out)
+	}
+
+	// 未配置allow时拒绝所有请求
+	buf.Reset()
+	h = httplog.ForceLevel("", nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		logger.WithContext(r.Context()).DebugLn("handle", r.URL.Path)
+	}))
+	req := httptest.NewRequest(http.MethodGet, "/anyone", nil)
+	req.Header.Set(httplog.DefaultForceLevelHeader, "debug")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+	if buf.Len() != 0 {
+		t.Fatalf("expect force level denied without allow, but get %q", buf.String())
+	}
+}
+
+func TestAccessLog(t *testing.T) {
+	rec := logtest.New(t)
+	var reqLogger logfactory.Logger
+	h := httplog.AccessLog(httplog.AccessConfig{
+		Logger: rec.Logger("access"),
+		Skip:   httplog.ExcludePaths("/health", "/static/*"),
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reqLogger = httplog.FromContext(r.Context())
+		switch r.URL.Path {
+		case "/missing":
+			http.NotFound(w, r)
+		case "/fail":
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			_, _ = w.Write([]byte("hello"))
+		}
+	}))
+
+	for _, path := range []string{"/hello?a=1", "/missing", "/fail", "/health", "/static/app.js"} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set(httplog.DefaultRequestIDHeader, "req"+path)
+		h.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	if len(rec.Entries()) != 3 {
+		t.Fatalf("expect excluded paths skipped, but get %+v", rec.Entries())
+	}
+	rec.AssertContains(logfactory.INFO, httplog.PathKey, "/hello", httplog.URIKey, "/hello?a=1",
+		httplog.StatusKey, 200, httplog.BytesKey, int64(5), httplog.RequestIDKey, "req/hello?a=1")
+	rec.AssertContains(logfactory.WARN, httplog.StatusKey, 404, httplog.MethodKey, http.MethodGet)
+	rec.AssertContains(logfactory.ERROR, httplog.StatusKey, 500, httplog.BytesKey, int64(0))
+	if _, ok := rec.Entries()[0].Fields[httplog.LatencyKey].(time.Duration); !ok {
+		t.Fatalf("expect latency field, but get %+v", rec.Entries()[0])
+	}
+
+	rec.Reset()
+	reqLogger.Info("in handler")
+	rec.AssertContains(logfactory.INFO, httplog.RequestIDKey, "req/static/app.js")
+
+	resp := httptest.NewRecorder()
+	h.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/", nil))
+	if resp.Header().Get(httplog.DefaultRequestIDHeader) == "" {
+		t.Fatal("expect generated request id in response header")
+	}
+}
+
+func TestCLFFormatter(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logging := logfactory.NewLogging()
+	logging.SetFormatter(&httplog.CLFFormatter{Combined: true})
+	logging.SetOutput(buf)
+	h := httplog.AccessLog(httplog.AccessConfig{
+		Logger: logfactory.NewFactory(logging).GetLogger(),
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("hello"))
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/hello?a=1", nil)
+	req.RemoteAddr = "10.0.0.1:1234"
+	req.SetBasicAuth("frank", "pass")
+	req.Header.Set("User-Agent", "test-agent")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+
+	line := buf.String()
+	if !strings.HasPrefix(line, "10.0.0.1 - frank [") ||
+		!strings.HasSuffix(line, `] "GET /hello?a=1 HTTP/1.1" 200 5 "-" "test-agent"`+"\n") {
+		t.Fatalf("unexpected combined log %q", line)
+	}
+}
@@ -0,0 +1,165 @@
+/*
+ * Copyright (c) 2022, AcmeStack
+ * All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package test
+
+import (
+	"github.com/acmestack/log4go/logfactory"
+	"github.com/acmestack/log4go/util"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func TestKeyValuesCloneIsolation(t *testing.T) {
+	parent := util.NewKeyValues("a", 1, "b", 2)
+	child := parent.Clone()
+	sibling := parent.Clone()
+
+	_ = child.Add("c", 3, "a", 10)
+	_ = sibling.Add("d", 4)
+	_ = child.Remove("b")
+	_ = parent.Add("e", 5)
+
+	expect := func(kvs util.KeyValues, keys []string, values ...interface{}) {
+		t.Helper()
+		if !reflect.DeepEqual(kvs.Keys(), keys) {
+			t.Fatalf("expect keys %v, but get %v", keys, kvs.Keys())
+		}
+		for i, k := range keys {
+			if kvs.Get(k) != values[i] {
+				t.Fatalf("expect %s=%v, but get %v", k, values[i], kvs.Get(k))
+			}
+		}
+	}
+	expect(parent, []string{"a", "b", "e"}, 1, 2, 5)
+	expect(child, []string{"a", "c"}, 10, 3)
+	expect(sibling, []string{"a", "b", "d"}, 1, 2, 4)
+
+	if err := child.Remove("b"); err == nil {
+		t.Fatal("expect error when remove missing key")
+	}
+	if err := child.Add(1, "v"); err == nil {
+		t.Fatal("expect error when key is not string")
+	}
+}
+
+func TestKeyValuesKeysImmutable(t *testing.T) {
+	kvs := util.NewKeyValues("b", 1, "a", 2)
+	keys := kvs.Keys()
+	sort.Strings(keys)
+	if !reflect.DeepEqual(kvs.Keys(), []string{"b", "a"}) {
+		t.Fatalf("sort keys must not change record, but get %v", kvs.Keys())
+	}
+
+	f := &util.TextFormatter{SortFunc: sort.Strings}
+	if err := f.Format(io.Discard, kvs); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(kvs.Keys(), []string{"b", "a"}) {
+		t.Fatalf("TextFormatter must not change record, but get %v", kvs.Keys())
+	}
+
+	var got []string
+	kvs.Range(func(key string, value interface{}) bool {
+		got = append(got, key)
+		return false
+	})
+	if !reflect.DeepEqual(got, []string{"b"}) {
+		t.Fatalf("expect Range stop after first key, but get %v", got)
+	}
+	it := kvs.Iterator()
+	for it.HasNext() {
+		k, v := it.Next()
+		if kvs.Get(k) != v {
+			t.Fatalf("unexpected iterator value %s=%v", k, v)
+		}
+	}
+}
+
+func TestKeyValuesConcurrentClone(t *testing.T) {
+	parent := util.NewKeyValues("root", 0)
+	wait := sync.WaitGroup{}
+	for i := 0; i < 8; i++ {
+		wait.Add(1)
+		go func(i int) {
+			defer wait.Done()
+			kvs := parent
+			for j := 0; j < 100; j++ {
+				kvs = kvs.Clone()
+				_ = kvs.Add("k"+strconv.Itoa(j), i)
+			}
+			if kvs.Len() != 101 || kvs.Get("k99") != i || kvs.Get("root") != 0 {
+				t.Errorf("unexpected key values %v", kvs.GetAll())
+			}
+		}(i)
+	}
+	wait.Wait()
+	if parent.Len() != 1 {
+		t.Fatalf("parent must not change, but get %v", parent.GetAll())
+	}
+}
+
+func benchmarkWithFieldsChain(b *testing.B, depth int) {
+	logging := logfactory.NewLogging(logfactory.SetCallerFlag(logfactory.CallerNone))
+	logging.SetOutput(io.Discard)
+	root := logfactory.NewFactory(logging).GetLogger("bench")
+	keys := make([]string, depth)
+	for i := range keys {
+		keys[i] = "key" + strconv.Itoa(i)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		logger := root
+		for _, k := range keys {
+			logger = logger.WithFields(k, i)
+		}
+		logger.Info("this is a benchmark message")
+	}
+}
+
+func BenchmarkWithFieldsChain1(b *testing.B) {
+	benchmarkWithFieldsChain(b, 1)
+}
+
+func BenchmarkWithFieldsChain8(b *testing.B) {
+	benchmarkWithFieldsChain(b, 8)
+}
+
+func BenchmarkWithFieldsChain32(b *testing.B) {
+	benchmarkWithFieldsChain(b, 32)
+}
+
+func BenchmarkLogWithFields(b *testing.B) {
+	logging := logfactory.NewLogging(logfactory.SetCallerFlag(logfactory.CallerNone))
+	logging.SetOutput(io.Discard)
+	logger := logfactory.NewFactory(logging).GetLogger("bench")
+	for i := 0; i < 8; i++ {
+		logger = logger.WithFields("key"+strconv.Itoa(i), i)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		logger.Info("this is a benchmark message")
+	}
+}
@@ -0,0 +1,78 @@
+/*
+ * Copyright (c) 2022, AcmeStack
+ * All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package test
+
+import (
+	"bytes"
+	"github.com/acmestack/log4go/logfactory"
+	"github.com/acmestack/log4go/util"
+	"strings"
+	"testing"
+)
+
+type userID int
+
+func (u userID) LogValue() interface{} {
+	return util.Lazy(func() interface{} {
+		return "user-" + strings.Repeat("x", int(u))
+	})
+}
+
+func TestLazyArgsAndFields(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logging := logfactory.NewLogging(logfactory.SetColorFlag(logfactory.DisableColor))
+	logging.SetOutput(buf)
+	calls := 0
+	expensive := util.Lazy(func() interface{} {
+		calls++
+		return "dump"
+	})
+	logger := logfactory.NewFactory(logging).GetLogger().WithFields("state", expensive)
+
+	logger.Debug("state: ", expensive)
+	logger.DebugF("state: %v", expensive)
+	logger.DebugLn("state:", expensive)
+	if calls != 0 || buf.Len() != 0 {
+		t.Fatalf("expect lazy value not evaluated for disabled level, calls: %d", calls)
+	}
+
+	logger.InfoF("state: %v %v", expensive, userID(2))
+	if calls != 2 || !strings.HasSuffix(buf.String(), "dump state: dump user-xx\n") {
+		t.Fatalf("expect lazy values resolved, calls: %d output: %q", calls, buf.String())
+	}
+}
+
+func TestFormatterResolveValue(t *testing.T) {
+	kvs := util.NewKeyValues("id", userID(1), "bad", util.Lazy(func() interface{} {
+		panic("boom")
+	}))
+	buf := &bytes.Buffer{}
+	if err := (&util.JsonFormatter{}).Format(buf, kvs); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != `{"bad":"!PANIC in LogValue: boom","id":"user-x"}` {
+		t.Fatalf("unexpected json %s", buf.String())
+	}
+	buf.Reset()
+	if err := (&util.TextFormatter{}).Format(buf, kvs); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "id=user-x bad=!PANIC in LogValue: boom \n" {
+		t.Fatalf("unexpected text %q", buf.String())
+	}
+}
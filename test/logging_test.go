@@ -0,0 +1,111 @@
+/*
+ * Copyright (c) 2022, AcmeStack
+ * All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package test
+
+import (
+	"bytes"
+	"errors"
+	"github.com/acmestack/log4go/logfactory"
+	"github.com/acmestack/log4go/util"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+type failWriter struct {
+	fail  bool
+	count int
+}
+
+func (w *failWriter) Write(d []byte) (int, error) {
+	w.count++
+	if w.fail {
+		return 0, errors.New("disk full")
+	}
+	return len(d), nil
+}
+
+func TestLoggingFallbackWriter(t *testing.T) {
+	primary := &failWriter{fail: true}
+	fallback := &bytes.Buffer{}
+	var errs []error
+	logging := logfactory.NewLogging(
+		logfactory.SetFallbackWriter(fallback),
+		logfactory.SetRetryCooldown(50*time.Millisecond),
+		logfactory.SetErrorHandler(func(level logfactory.Level, err error) {
+			errs = append(errs, err)
+		}))
+	logging.SetOutput(primary)
+	logger := logfactory.NewFactory(logging).GetLogger()
+
+	logger.Info("first")
+	logger.Info("second")
+	if primary.count != 1 || len(errs) != 1 || logging.FailedWrites() != 1 {
+		t.Fatalf("expect primary skipped after failure, writes: %d errors: %v", primary.count, errs)
+	}
+	if !strings.Contains(fallback.String(), "first") || !strings.Contains(fallback.String(), "second") {
+		t.Fatalf("expect logs in fallback writer, but get %q", fallback.String())
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	primary.fail = false
+	logger.Info("third")
+	if primary.count != 2 || strings.Contains(fallback.String(), "third") {
+		t.Fatalf("expect primary retried after cooldown, writes: %d", primary.count)
+	}
+}
+
+func TestLoggingErrorHandlerWithoutFallback(t *testing.T) {
+	primary := &failWriter{fail: true}
+	var count int
+	logging := logfactory.NewLogging(
+		logfactory.SetErrorHandler(func(level logfactory.Level, err error) {
+			count++
+		}))
+	logging.SetOutput(primary)
+	logger := logfactory.NewFactory(logging).GetLogger()
+
+	logger.Info("first")
+	logger.Info("second")
+	if primary.count != 2 || count != 2 || logging.FailedWrites() != 2 {
+		t.Fatalf("expect every failure reported, writes: %d errors: %d", primary.count, count)
+	}
+}
+
+type failFormatter struct{}
+
+func (f failFormatter) Format(writer io.Writer, keyValues util.KeyValues) error {
+	return errors.New("format failed")
+}
+
+func TestLoggingFormatterError(t *testing.T) {
+	w := &failWriter{}
+	var errs []error
+	logging := logfactory.NewLogging(
+		logfactory.SetErrorHandler(func(level logfactory.Level, err error) {
+			errs = append(errs, err)
+		}))
+	logging.SetFormatter(failFormatter{})
+	logging.SetOutput(w)
+
+	logfactory.NewFactory(logging).GetLogger().Info("test")
+	if w.count != 0 || len(errs) != 1 || logging.FailedWrites() != 1 {
+		t.Fatalf("expect format failure counted, writes: %d errors: %v failed: %d", w.count, errs, logging.FailedWrites())
+	}
+}
@@ -0,0 +1,129 @@
+/*
+ * Copyright (c) 2022, AcmeStack
+ * All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package test
+
+import (
+	"github.com/acmestack/log4go/log"
+	"github.com/acmestack/log4go/logfactory"
+	"github.com/acmestack/log4go/logtest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestLogPackageDelegates(t *testing.T) {
+	rec := logtest.New(t, logfactory.SetLogLevel(logfactory.INFO))
+	rec.InstallGlobal()
+
+	log.Debug("skipped")
+	log.InfoT("user {User}", "alice")
+	log.WithFields("RequestID", 1).WithName("svc").WarnLn("derived")
+	log.Error("plain")
+	if log.DebugEnabled() || !log.InfoEnabled() {
+		t.Fatal("unexpected enabled levels")
+	}
+	log.SetLevel(logfactory.DEBUG)
+	log.DebugF("enabled %d", 1)
+
+	entries := rec.Entries()
+	if len(entries) != 4 {
+		t.Fatalf("expect 4 entries, but get %+v", entries)
+	}
+	for _, e := range entries {
+		if !strings.HasPrefix(e.Caller, "logpkg_test.go") {
+			t.Fatalf("expect caller at call site, but get %+v", e)
+		}
+	}
+	rec.AssertContains(logfactory.INFO, "User", "alice")
+	rec.AssertContains(logfactory.WARN, "RequestID", 1)
+	if entries[1].Name != "svc" || len(entries[2].Fields) != 0 {
+		t.Fatalf("expect WithFields not to affect package functions, but get %+v", entries)
+	}
+}
+
+func TestLogPackageConcurrentReset(t *testing.T) {
+	rec := logtest.New(t)
+	rec.InstallGlobal()
+
+	wait := sync.WaitGroup{}
+	for i := 0; i < 4; i++ {
+		wait.Add(2)
+		go func() {
+			defer wait.Done()
+			for j := 0; j < 100; j++ {
+				log.InfoLn("concurrent", j)
+			}
+		}()
+		go func() {
+			defer wait.Done()
+			for j := 0; j < 20; j++ {
+				logfactory.ResetLogging(rec.Logging())
+				log.SetLevel(logfactory.DEBUG)
+			}
+		}()
+	}
+	wait.Wait()
+	if len(rec.Entries()) != 400 {
+		t.Fatalf("expect 400 entries, but get %d", len(rec.Entries()))
+	}
+}
+
+// uncomparableLogging 动态类型不可比较的Logging
+type uncomparableLogging struct {
+	logfactory.Logging
+	tags []string
+}
+
+func TestLogPackageUncomparableLogging(t *testing.T) {
+	rec := logtest.New(t)
+	rec.InstallGlobal()
+
+	logfactory.ResetLogging(uncomparableLogging{Logging: rec.Logging()})
+	log.Info("first")
+	log.Info("second")
+	logfactory.ResetLogging(rec.Logging())
+	log.Info("third")
+	if n := len(rec.Entries()); n != 3 {
+		t.Fatalf("expect 3 entries, but get %d", n)
+	}
+}
+
+func TestLogPackageFactoryReset(t *testing.T) {
+	log.Info("before install")
+	rec := logtest.New(t)
+	rec.Install(logfactory.GetFactory())
+
+	log.Info("after install")
+	rec.AssertMessage(logfactory.INFO, "after install")
+}
+
+func recoverWithPackage() {
+	defer log.RecoverAndLog()
+	panic("package boom")
+}
+
+func TestLogPackageRecoverAndLog(t *testing.T) {
+	rec := logtest.New(t)
+	rec.InstallGlobal()
+
+	recoverWithPackage()
+	entries := rec.Find(logfactory.ERROR, logfactory.PanicKey, "package boom")
+	if len(entries) != 1 || !strings.HasPrefix(entries[0].Caller, "logpkg_test.go") {
+		t.Fatalf("expect panic logged at panic site, but get %+v", rec.Entries())
+	}
+}
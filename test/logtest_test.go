@@ -0,0 +1,69 @@
+/*
+ * Copyright (c) 2022, AcmeStack
+ * All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package test
+
+import (
+	"github.com/acmestack/log4go/ext"
+	"github.com/acmestack/log4go/logfactory"
+	"github.com/acmestack/log4go/logtest"
+	"testing"
+)
+
+func TestLogtestRecorder(t *testing.T) {
+	rec := logtest.New(t)
+	logger := rec.Logger("test").WithFields("User", "alice")
+	logger.Debug("debug message")
+	logger.InfoF("user %s logged in", "alice")
+	logger.Fatal("fatal message")
+
+	rec.AssertContains(logfactory.INFO, "User", "alice")
+	rec.AssertMessage(logfactory.DEBUG, "debug message")
+	if len(rec.Find(logfactory.WARN)) != 0 {
+		t.Fatal("expect no warn log")
+	}
+	if codes := rec.ExitCodes(); len(codes) != 1 {
+		t.Fatalf("expect fatal recorded, but get %v", codes)
+	}
+	e := rec.Find(logfactory.INFO)[0]
+	if e.Name != "test" || e.Message != "user alice logged in" || e.Caller == "" {
+		t.Fatalf("unexpected entry %+v", e)
+	}
+
+	rec.Reset()
+	logger.Info("info message")
+	rec.AssertNoErrors()
+}
+
+func TestLogtestInstall(t *testing.T) {
+	t.Run("factory", func(t *testing.T) {
+		fac := logfactory.NewFactory(logfactory.NewLogging())
+		rec := logtest.New(t)
+		rec.Install(fac)
+		fac.GetLogger().Warn("warn message")
+		rec.AssertMessage(logfactory.WARN, "warn message")
+	})
+
+	t.Run("mutable factory", func(t *testing.T) {
+		fac := ext.NewMutableFactory(logfactory.NewLogging())
+		logger := fac.GetLogger()
+		rec := logtest.New(t)
+		rec.Install(fac)
+		logger.Error("error message")
+		rec.AssertMessage(logfactory.ERROR, "error message")
+	})
+}
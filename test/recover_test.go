@@ -0,0 +1,117 @@
+/*
+ * Copyright (c) 2022, AcmeStack
+ * All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package test
+
+import (
+	"bytes"
+	"errors"
+	"github.com/acmestack/log4go/ext"
+	"github.com/acmestack/log4go/logfactory"
+	"github.com/acmestack/log4go/logtest"
+	"github.com/acmestack/log4go/util"
+	"strings"
+	"testing"
+	"time"
+)
+
+func panicWithRecover(logger logfactory.Logger) {
+	defer logger.RecoverAndLog()
+	panic("boom")
+}
+
+func TestRecoverAndLog(t *testing.T) {
+	rec := logtest.New(t)
+	loggers := map[string]logfactory.Logger{
+		"default": rec.Logger().WithFields("RequestID", 1),
+		"mutable": ext.NewMutableFactory(rec.Logging()).GetLogger().WithFields("RequestID", 1),
+	}
+	for name, logger := range loggers {
+		t.Run(name, func(t *testing.T) {
+			rec.Reset()
+			panicWithRecover(logger)
+
+			entries := rec.Find(logfactory.ERROR, logfactory.PanicKey, "boom", "RequestID", 1)
+			if len(entries) != 1 {
+				t.Fatalf("expect panic logged, but get %+v", rec.Entries())
+			}
+			e := entries[0]
+			stack := e.Fields[logfactory.StackKey].(logfactory.Stack).String()
+			if !strings.Contains(stack, "panicWithRecover") || strings.Contains(stack, "logfactory.") {
+				t.Fatalf("expect stack of panicking goroutine only, but get %s", stack)
+			}
+			if !strings.HasPrefix(e.Caller, "recover_test.go") {
+				t.Fatalf("expect caller at panic site, but get %s", e.Caller)
+			}
+		})
+	}
+}
+
+func TestLoggerGo(t *testing.T) {
+	rec := logtest.New(t)
+	rec.Logger().Go(func() {
+		panic(errors.New("goroutine boom"))
+	})
+
+	deadline := time.Now().Add(time.Second)
+	for len(rec.Find(logfactory.ERROR)) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("expect panic in goroutine logged")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	rec.AssertMessage(logfactory.ERROR, "goroutine boom")
+}
+
+func TestPanicValue(t *testing.T) {
+	var v interface{}
+	logging := logfactory.NewLogging(logfactory.SetPanicFunc(func(i interface{}) {
+		v = i
+	}))
+	logging.SetOutput(&failWriter{})
+	logger := logfactory.NewFactory(logging).GetLogger().WithFields("key", "value")
+
+	logger.PanicF("panic %d", 1)
+	err, ok := v.(*logfactory.PanicError)
+	if !ok || err.Error() != "panic 1" || err.Fields.Get("key") != "value" {
+		t.Fatalf("expect *PanicError but get %#v", v)
+	}
+
+	logging = logfactory.NewLogging(
+		logfactory.SetPanicValueFunc(logfactory.PanicKeyValues),
+		logfactory.SetPanicFunc(func(i interface{}) {
+			v = i
+		}))
+	logging.SetOutput(&failWriter{})
+	logfactory.NewFactory(logging).GetLogger().Panic("legacy")
+	if kvs, ok := v.(util.KeyValues); !ok || kvs.Get(logfactory.ContentKey) != "legacy" {
+		t.Fatalf("expect util.KeyValues but get %#v", v)
+	}
+}
+
+func TestRecoverAndLogText(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logging := logfactory.NewLogging(logfactory.SetColorFlag(logfactory.DisableColor))
+	logging.SetOutput(buf)
+	panicWithRecover(logfactory.NewFactory(logging).GetLogger())
+
+	lines := strings.Split(buf.String(), "\n")
+	if len(lines) < 3 || !strings.HasSuffix(lines[0], " recovered from panic: boom") ||
+		strings.Count(lines[0], "boom") != 1 || !strings.Contains(buf.String(), "\ngithub.com/acmestack/log4go/test.panicWithRecover\n") {
+		t.Fatalf("expect panic value once and stack in text output, but get %q", buf.String())
+	}
+}
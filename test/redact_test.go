@@ -0,0 +1,87 @@
+/*
+ * Copyright (c) 2022, AcmeStack
+ * All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/acmestack/log4go/logfactory"
+	"github.com/acmestack/log4go/redact"
+	"github.com/acmestack/log4go/util"
+	"strings"
+	"testing"
+)
+
+type password string
+
+func (p password) Redacted() interface{} {
+	return "******"
+}
+
+func TestRedactJson(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logging := logfactory.NewLogging(logfactory.SetRedactor(redact.New()))
+	logging.SetFormatter(&util.JsonFormatter{})
+	logging.SetOutput(buf)
+	logger := logfactory.NewFactory(logging).GetLogger().WithFields(
+		"user_password", "p@ss", "Api_Key", 123, "contact", "mail frank@example.com", "order", 42)
+
+	logger.InfoF("charge card 4111 1111 1111 1111, ref 1234567890123, auth Bearer abc.def-123")
+	var v map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &v); err != nil {
+		t.Fatal(err)
+	}
+	expect := map[string]interface{}{
+		"user_password":       redact.DefaultMask,
+		"Api_Key":             redact.DefaultMask,
+		"contact":             "mail " + redact.DefaultMask,
+		"order":               float64(42),
+		logfactory.ContentKey: "charge card [REDACTED], ref 1234567890123, auth Bearer [REDACTED]\n",
+	}
+	for k, e := range expect {
+		if v[k] != e {
+			t.Fatalf("expect %s: %v, but get %v", k, e, v[k])
+		}
+	}
+}
+
+func TestRedactable(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logging := logfactory.NewLogging(logfactory.SetColorFlag(logfactory.DisableColor))
+	logging.SetOutput(buf)
+	logger := logfactory.NewFactory(logging).GetLogger()
+
+	pwd := password("p@ss")
+	logger.WithFields("pwd", pwd).InfoLn("login with", pwd)
+	if out := buf.String(); strings.Contains(out, "p@ss") || strings.Count(out, "******") != 2 {
+		t.Fatalf("expect Redactable values redacted without Redactor, but get %q", out)
+	}
+}
+
+func TestLuhn(t *testing.T) {
+	for s, expect := range map[string]bool{
+		"4111-1111-1111-1111": true,
+		"5500 0000 0000 0004": true,
+		"4111111111111112":    false,
+		"":                    false,
+	} {
+		if redact.Luhn(s) != expect {
+			t.Fatalf("luhn %q expect %v", s, expect)
+		}
+	}
+}
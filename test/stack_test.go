@@ -0,0 +1,74 @@
+/*
+ * Copyright (c) 2022, AcmeStack
+ * All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/acmestack/log4go/logfactory"
+	"github.com/acmestack/log4go/util"
+	"strings"
+	"testing"
+)
+
+func logWithStack(logger logfactory.Logger) {
+	logger.WithStack().Error("failed")
+}
+
+func TestWithStackJson(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logging := logfactory.NewLogging()
+	logging.SetFormatter(&util.JsonFormatter{})
+	logging.SetOutput(buf)
+	logWithStack(logfactory.NewFactory(logging).GetLogger())
+
+	var v struct {
+		Stack []logfactory.StackFrame `json:"LogStack"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &v); err != nil {
+		t.Fatalf("unmarshal %q failed: %v", buf.String(), err)
+	}
+	if len(v.Stack) == 0 || !strings.HasSuffix(v.Stack[0].Function, "test.logWithStack") {
+		t.Fatalf("expect stack start at caller, but get %s", buf.String())
+	}
+	for _, f := range v.Stack {
+		if strings.Contains(f.Function, "log4go/logfactory.") {
+			t.Fatalf("expect log4go frames filtered, but get %+v", f)
+		}
+	}
+}
+
+func TestStackModeByLevel(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logging := logfactory.NewLogging(
+		logfactory.SetColorFlag(logfactory.DisableColor),
+		logfactory.SetStackMode(logfactory.WARN, logfactory.StackCurrent))
+	logging.SetOutput(buf)
+	logger := logfactory.NewFactory(logging).GetLogger()
+
+	logger.Info("info")
+	if strings.Contains(buf.String(), "TestStackModeByLevel") {
+		t.Fatalf("expect no stack for INFO, but get %q", buf.String())
+	}
+	buf.Reset()
+	logger.Warn("warn")
+	lines := strings.Split(buf.String(), "\n")
+	if len(lines) < 3 || !strings.HasSuffix(lines[0], "warn") || !strings.HasSuffix(lines[1], "test.TestStackModeByLevel") {
+		t.Fatalf("expect stack after message, but get %q", buf.String())
+	}
+}
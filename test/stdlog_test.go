@@ -0,0 +1,68 @@
+/*
+ * Copyright (c) 2022, AcmeStack
+ * All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package test
+
+import (
+	"github.com/acmestack/log4go/logfactory"
+	"github.com/acmestack/log4go/logtest"
+	"github.com/acmestack/log4go/stdlog"
+	"log"
+	"strings"
+	"testing"
+)
+
+func TestStdLogWriter(t *testing.T) {
+	rec := logtest.New(t)
+	flags := log.Ldate | log.Lmicroseconds | log.Lshortfile | log.Lmsgprefix
+	std := log.New(stdlog.NewWriter(rec.Logger(), logfactory.WARN, flags, "[db] "), "[db] ", flags)
+	std.Printf("slow query %d ms", 200)
+
+	rec.AssertMessage(logfactory.WARN, "slow query 200 ms")
+	e := rec.Entries()[0]
+	if e.Message != "slow query 200 ms" || !strings.HasPrefix(e.Caller, "stdlog_test.go") {
+		t.Fatalf("expect std prefix removed and caller at call site, but get %+v", e)
+	}
+}
+
+func TestRedirectStdLog(t *testing.T) {
+	rec := logtest.New(t)
+	restore := stdlog.RedirectStdLog(rec.Logger("std"), logfactory.INFO)
+	log.Println("from std", 1)
+	restore()
+
+	e := rec.Entries()[0]
+	if e.Level != logfactory.INFO || e.Name != "std" || e.Message != "from std 1" || !strings.HasPrefix(e.Caller, "stdlog_test.go") {
+		t.Fatalf("unexpected entry %+v", e)
+	}
+	if log.Flags() != log.LstdFlags {
+		t.Fatalf("expect std flags restored, but get %d", log.Flags())
+	}
+}
+
+func TestPrinter(t *testing.T) {
+	rec := logtest.New(t)
+	var p stdlog.Printer = stdlog.NewPrinter(rec.Logger(), logfactory.DEBUG)
+	p.Printf("connect %s\n", "db")
+	stdlog.NewPrinter(rec.Logger(), logfactory.ERROR).Println("failed", 3)
+
+	entries := rec.Entries()
+	if len(entries) != 2 || entries[0].Message != "connect db" || entries[1].Message != "failed 3" ||
+		!strings.HasPrefix(entries[0].Caller, "stdlog_test.go") || !strings.HasPrefix(entries[1].Caller, "stdlog_test.go") {
+		t.Fatalf("unexpected entries %+v", entries)
+	}
+}
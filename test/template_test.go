@@ -0,0 +1,118 @@
+/*
+ * Copyright (c) 2022, AcmeStack
+ * All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package test
+
+import (
+	"bytes"
+	"errors"
+	"github.com/acmestack/log4go/logfactory"
+	"github.com/acmestack/log4go/logtest"
+	"github.com/acmestack/log4go/util"
+	"strings"
+	"testing"
+)
+
+func TestTemplateFields(t *testing.T) {
+	rec := logtest.New(t)
+	logger := rec.Logger().WithFields("RequestID", 7)
+	logger.InfoT("user {User} logged in from {IP}, welcome {User}", "alice", "10.0.0.1")
+	logger.WarnT("{{literal}} {bad name} {Count} {Missing}", 3)
+
+	rec.AssertContains(logfactory.INFO, "RequestID", 7, "User", "alice", "IP", "10.0.0.1",
+		logfactory.TemplateKey, "user {User} logged in from {IP}, welcome {User}")
+	rec.AssertMessage(logfactory.INFO, "user alice logged in from 10.0.0.1, welcome alice")
+	rec.AssertContains(logfactory.WARN, "Count", 3)
+	e := rec.Find(logfactory.WARN)[0]
+	if e.Message != "{literal} {bad name} 3 {Missing}" {
+		t.Fatalf("unexpected message %q", e.Message)
+	}
+	if _, ok := e.Fields["Missing"]; ok {
+		t.Fatalf("expect no field for missing argument, but get %+v", e.Fields)
+	}
+}
+
+func TestTemplateText(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logging := logfactory.NewLogging(logfactory.SetColorFlag(logfactory.DisableColor))
+	logging.SetOutput(buf)
+	logfactory.NewFactory(logging).GetLogger().WithFields("RequestID", 7).InfoT("user {User} logged in", "alice")
+	if !strings.HasSuffix(buf.String(), "] template_test.go:53 7 user alice logged in\n") {
+		t.Fatalf("expect template fields not repeated in text output, but get %q", buf.String())
+	}
+}
+
+func TestTemplateFieldCollision(t *testing.T) {
+	logT := func(opts ...logfactory.LoggingOpt) string {
+		buf := &bytes.Buffer{}
+		logging := logfactory.NewLogging(append(opts, logfactory.SetColorFlag(logfactory.DisableColor))...)
+		logging.SetFormatter(&util.JsonFormatter{})
+		logging.SetOutput(buf)
+		logfactory.NewFactory(logging).GetLogger().WithFields("User", "bob").InfoT("user {User} logged in", "alice")
+		return buf.String()
+	}
+
+	if out := logT(); !strings.Contains(out, `"User":"bob"`) || !strings.Contains(out, `"User_":"alice"`) {
+		t.Fatalf("expect placeholder field renamed by default, but get %s", out)
+	}
+	if out := logT(logfactory.SetKeyCollision(logfactory.CollisionPrefix, "")); !strings.Contains(out, `"fields.User":"bob"`) ||
+		!strings.Contains(out, `"fields.User_":"alice"`) || !strings.Contains(out, `"`+logfactory.TemplateKey+`":`) {
+		t.Fatalf("expect placeholder field renamed and prefixed, but get %s", out)
+	}
+	if out := logT(logfactory.SetKeyCollision(logfactory.CollisionOverwrite, "")); !strings.Contains(out, `"User":"alice"`) ||
+		strings.Contains(out, "bob") {
+		t.Fatalf("expect placeholder overwrite field, but get %s", out)
+	}
+	var errs []error
+	out := logT(logfactory.SetKeyCollision(logfactory.CollisionReject, ""),
+		logfactory.SetErrorHandler(func(level logfactory.Level, err error) {
+			errs = append(errs, err)
+		}))
+	if !strings.Contains(out, `"User":"bob"`) || strings.Contains(out, `"User_"`) ||
+		len(errs) != 1 || !errors.Is(errs[0], logfactory.ErrKeyCollision) {
+		t.Fatalf("expect placeholder field rejected, but get %s %v", out, errs)
+	}
+
+	buf := &bytes.Buffer{}
+	logging := logfactory.NewLogging(logfactory.SetColorFlag(logfactory.DisableColor),
+		logfactory.SetKeyCollision(logfactory.CollisionRename, ""))
+	logging.SetOutput(buf)
+	logfactory.NewFactory(logging).GetLogger().WithFields("User", "bob").InfoT("user {User} logged in", "alice")
+	if !strings.HasSuffix(buf.String(), " bob user alice logged in\n") {
+		t.Fatalf("expect renamed placeholder field not repeated in text output, but get %q", buf.String())
+	}
+}
+
+func TestTemplateCache(t *testing.T) {
+	for _, size := range []int{logfactory.DefaultTemplateCacheSize, 1, 0} {
+		rec := logtest.New(t, logfactory.SetTemplateCacheSize(size))
+		logger := rec.Logger()
+		for i, template := range []string{"a {A}", "b {B}", "a {A}"} {
+			// 同一调用位置使用不同的模板时重新解析
+			logger.InfoT(template, i)
+			logger.InfoT("c {C}", i)
+		}
+		var messages []string
+		for _, e := range rec.Find(logfactory.INFO) {
+			messages = append(messages, e.Message)
+		}
+		if expect := "a 0,c 0,b 1,c 1,a 2,c 2"; strings.Join(messages, ",") != expect {
+			t.Fatalf("size %d: expect %s, but get %v", size, expect, messages)
+		}
+		rec.AssertContains(logfactory.INFO, "B", 1, logfactory.TemplateKey, "b {B}")
+	}
+}
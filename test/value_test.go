@@ -0,0 +1,97 @@
+/*
+ * Copyright (c) 2022, AcmeStack
+ * All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package test
+
+import (
+	"bytes"
+	"github.com/acmestack/log4go/ext"
+	"github.com/acmestack/log4go/logfactory"
+	"github.com/acmestack/log4go/util"
+	"io"
+	"os"
+	"sync"
+	"testing"
+)
+
+func TestAtomicValue(t *testing.T) {
+	v := util.NewAtomic[io.Writer](os.Stdout)
+	v.Store(&bytes.Buffer{})
+	if _, ok := v.Load().(*bytes.Buffer); !ok {
+		t.Fatalf("expect stored value with different concrete type, but get %T", v.Load())
+	}
+	if allocs := testing.AllocsPerRun(100, func() {
+		_ = v.Load()
+	}); allocs != 0 {
+		t.Fatalf("expect no allocation on Load, but get %v", allocs)
+	}
+	w := io.Writer(os.Stderr)
+	if allocs := testing.AllocsPerRun(100, func() {
+		v.Store(w)
+	}); allocs != 1 {
+		t.Fatalf("expect only the stored copy allocated on Store, but get %v", allocs)
+	}
+	var empty util.Atomic[io.Writer]
+	if empty.Load() != nil {
+		t.Fatal("expect zero value before Store")
+	}
+
+	var compat util.Value = util.NewAtomicValue(1)
+	compat.Store("s")
+	if compat.Load() != "s" {
+		t.Fatalf("unexpected value %v", compat.Load())
+	}
+}
+
+func TestConcurrentResetAndLogging(t *testing.T) {
+	old := logfactory.GetFactory()
+	defer logfactory.ResetFactory(old)
+
+	newLogging := func() logfactory.Logging {
+		logging := logfactory.NewLogging()
+		logging.SetOutput(io.Discard)
+		return logging
+	}
+	wait := sync.WaitGroup{}
+	for i := 0; i < 4; i++ {
+		wait.Add(3)
+		go func() {
+			defer wait.Done()
+			for j := 0; j < 50; j++ {
+				logfactory.ResetFactory(logfactory.NewFactory(newLogging()))
+				logfactory.ResetFactory(ext.NewMutableFactory(newLogging()))
+			}
+		}()
+		go func() {
+			defer wait.Done()
+			for j := 0; j < 50; j++ {
+				logfactory.ResetLogging(newLogging())
+			}
+		}()
+		go func() {
+			defer wait.Done()
+			for j := 0; j < 200; j++ {
+				logfactory.GetLogger("concurrent").Info("log ", j)
+				_ = logfactory.DefaultLogging().IsEnabled(logfactory.INFO)
+			}
+		}()
+	}
+	wait.Wait()
+	if logfactory.GetFactory().GetLogging() != logfactory.DefaultLogging() {
+		t.Fatal("expect global factory and logging consistent")
+	}
+}
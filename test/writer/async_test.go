@@ -0,0 +1,178 @@
+/*
+ * Copyright (c) 2022, AcmeStack
+ * All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package writer
+
+import (
+	"errors"
+	"github.com/acmestack/log4go/writer"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type slowWriter struct {
+	delay time.Duration
+	count int
+	err   error
+}
+
+func (w *slowWriter) Write(d []byte) (int, error) {
+	time.Sleep(w.delay)
+	w.count++
+	return len(d), w.err
+}
+
+func TestAsyncWriterDrainOnClose(t *testing.T) {
+	sw := &slowWriter{delay: time.Millisecond}
+	w := writer.NewAsyncWriterWithConfig(sw, nil, writer.AsyncConfig{
+		BufferSize:   100,
+		Policy:       writer.PolicyBlock,
+		DrainTimeout: time.Second,
+	})
+	for i := 0; i < 50; i++ {
+		_, _ = w.Write([]byte("test\n"))
+	}
+	_ = w.Close()
+
+	stats := w.Stats()
+	if sw.count != 50 || stats.Written != 50 || stats.Enqueued != 50 || stats.Dropped != 0 {
+		t.Fatalf("expect all data written, count: %d stats: %+v", sw.count, stats)
+	}
+	if _, err := w.Write([]byte("test\n")); !errors.Is(err, writer.ErrWriterClosed) {
+		t.Fatalf("expect ErrWriterClosed but get %v", err)
+	}
+}
+
+func TestAsyncWriterDropPolicy(t *testing.T) {
+	t.Run("drop newest", func(t *testing.T) {
+		sw := &slowWriter{delay: 10 * time.Millisecond}
+		w := writer.NewAsyncWriterWithConfig(sw, nil, writer.AsyncConfig{
+			BufferSize:   1,
+			Policy:       writer.PolicyDropNewest,
+			DrainTimeout: -1,
+		})
+		var dropped int
+		for i := 0; i < 10; i++ {
+			if _, err := w.Write([]byte("test\n")); errors.Is(err, writer.ErrDropped) {
+				dropped++
+			}
+		}
+		_ = w.Close()
+		stats := w.Stats()
+		if dropped == 0 || stats.Enqueued+uint64(dropped) != 10 {
+			t.Fatalf("expect data dropped, dropped: %d stats: %+v", dropped, stats)
+		}
+	})
+
+	t.Run("drop oldest", func(t *testing.T) {
+		sw := &slowWriter{delay: 10 * time.Millisecond}
+		w := writer.NewAsyncWriterWithConfig(sw, nil, writer.AsyncConfig{
+			BufferSize: 1,
+			Policy:     writer.PolicyDropOldest,
+		})
+		for i := 0; i < 10; i++ {
+			if _, err := w.Write([]byte("test\n")); err != nil {
+				t.Fatal(err)
+			}
+		}
+		_ = w.Close()
+		stats := w.Stats()
+		if stats.Enqueued != 10 || stats.Dropped == 0 || stats.Written+stats.Dropped != 10 {
+			t.Fatalf("expect oldest data dropped, stats: %+v", stats)
+		}
+	})
+
+	t.Run("block timeout", func(t *testing.T) {
+		sw := &slowWriter{delay: 50 * time.Millisecond}
+		w := writer.NewAsyncWriterWithConfig(sw, nil, writer.AsyncConfig{
+			BufferSize:   1,
+			Policy:       writer.PolicyBlockTimeout,
+			BlockTimeout: time.Millisecond,
+		})
+		var err error
+		for i := 0; i < 5 && err == nil; i++ {
+			_, err = w.Write([]byte("test\n"))
+		}
+		_ = w.Close()
+		if !errors.Is(err, writer.ErrDropped) {
+			t.Fatalf("expect ErrDropped but get %v", err)
+		}
+	})
+}
+
+func TestAsyncWriterOnError(t *testing.T) {
+	werr := errors.New("disk full")
+	var errs []error
+	w := writer.NewAsyncWriterWithConfig(&slowWriter{err: werr}, nil, writer.AsyncConfig{
+		BufferSize: 10,
+		OnError: func(err error) {
+			errs = append(errs, err)
+		},
+	})
+	_, _ = w.Write([]byte("test\n"))
+	_ = w.Close()
+	if len(errs) != 1 || errs[0] != werr || w.Stats().WriteErrors != 1 {
+		t.Fatalf("expect write error reported, errors: %v stats: %+v", errs, w.Stats())
+	}
+}
+
+func TestAsyncWriterCloseWhileWriting(t *testing.T) {
+	for i := 0; i < 20; i++ {
+		w := writer.NewAsyncWriterWithConfig(&slowWriter{}, nil, writer.AsyncConfig{
+			BufferSize:   4,
+			Policy:       writer.PolicyBlock,
+			DrainTimeout: time.Second,
+		})
+		var accepted uint64
+		wait := sync.WaitGroup{}
+		for j := 0; j < 8; j++ {
+			wait.Add(1)
+			go func() {
+				defer wait.Done()
+				for k := 0; k < 100; k++ {
+					if _, err := w.Write([]byte("test\n")); err == nil {
+						atomic.AddUint64(&accepted, 1)
+					}
+				}
+			}()
+		}
+		time.Sleep(time.Millisecond)
+		_ = w.Close()
+		wait.Wait()
+
+		stats := w.Stats()
+		if stats.Enqueued != atomic.LoadUint64(&accepted) || stats.Written+stats.Dropped != stats.Enqueued {
+			t.Fatalf("expect every accepted record written or dropped, accepted: %d stats: %+v", accepted, stats)
+		}
+	}
+}
+
+func TestAsyncWriterDefaultBlockTimeout(t *testing.T) {
+	sw := &slowWriter{delay: 20 * time.Millisecond}
+	w := writer.NewAsyncWriterWithConfig(sw, nil, writer.AsyncConfig{
+		BufferSize: 1,
+		Policy:     writer.PolicyBlockTimeout,
+	})
+	defer w.Close()
+	for i := 0; i < 3; i++ {
+		if _, err := w.Write([]byte("test\n")); err != nil {
+			t.Fatalf("expect write blocked with default timeout but get %v", err)
+		}
+	}
+}
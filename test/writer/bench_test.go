@@ -0,0 +1,55 @@
+/*
+ * Copyright (c) 2022, AcmeStack
+ * All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package writer
+
+import (
+	"github.com/acmestack/log4go/logfactory"
+	"github.com/acmestack/log4go/writer"
+	"io"
+	"testing"
+)
+
+func benchmarkParallelLog(b *testing.B, w io.Writer) {
+	logging := logfactory.NewLogging(logfactory.SetCallerFlag(logfactory.CallerNone))
+	logging.SetOutput(w)
+	logger := logfactory.NewFactory(logging).GetLogger("bench").WithFields("key", "value")
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			logger.Info("this is a benchmark message")
+		}
+	})
+}
+
+func BenchmarkParallelLogRingBufferWriter(b *testing.B) {
+	w := writer.NewRingBufferWriter(io.Discard, nil)
+	defer w.Close()
+	benchmarkParallelLog(b, w)
+}
+
+func BenchmarkParallelLogAsyncBufferLogWriter(b *testing.B) {
+	w := writer.NewAsyncBufferWriter(io.Discard, nil)
+	defer w.Close()
+	benchmarkParallelLog(b, w)
+}
+
+func BenchmarkParallelLogLockedWriter(b *testing.B) {
+	benchmarkParallelLog(b, &writer.LockedWriter{W: io.Discard})
+}
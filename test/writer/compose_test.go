@@ -0,0 +1,142 @@
+/*
+ * Copyright (c) 2022, AcmeStack
+ * All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package writer
+
+import (
+	"bytes"
+	"errors"
+	"github.com/acmestack/log4go/appender"
+	"github.com/acmestack/log4go/logfactory"
+	"github.com/acmestack/log4go/util"
+	"github.com/acmestack/log4go/writer"
+	"strings"
+	"testing"
+)
+
+func TestTee(t *testing.T) {
+	b1, b2 := &syncBuffer{}, &syncBuffer{}
+	failed := &slowWriter{err: errors.New("failed")}
+	w := writer.NewTee(b1, failed, b2)
+	_, err := w.Write([]byte("test\n"))
+	if b1.String() != "test\n" || b2.String() != "test\n" {
+		t.Fatalf("expect data written to all writers")
+	}
+	var errs writer.MultiError
+	if !errors.As(err, &errs) || len(errs) != 1 {
+		t.Fatalf("expect aggregated error but get %v", err)
+	}
+	_ = w.Close()
+	if !b1.closed || !b2.closed {
+		t.Fatal("expect close propagated")
+	}
+}
+
+func TestFailover(t *testing.T) {
+	failed := &slowWriter{err: errors.New("failed")}
+	b1, b2 := &syncBuffer{}, &syncBuffer{}
+	w := writer.NewFailover(failed, b1, b2)
+	if _, err := w.Write([]byte("test\n")); err != nil {
+		t.Fatal(err)
+	}
+	if failed.count != 1 || b1.String() != "test\n" || b2.String() != "" {
+		t.Fatal("expect data written to first available writer")
+	}
+
+	w = writer.NewFailover(failed, failed)
+	if _, err := w.Write([]byte("test\n")); err == nil {
+		t.Fatal("expect error when all writers failed")
+	}
+}
+
+func TestLevelRouterWithLogging(t *testing.T) {
+	def, errOut := &bytes.Buffer{}, &bytes.Buffer{}
+	router := writer.NewLevelRouter(def).Route(logfactory.ERROR, errOut)
+
+	logging := logfactory.NewLogging()
+	logging.SetOutput(&writer.LockedWriter{W: router})
+	logger := logfactory.NewFactory(logging).GetLogger()
+	logger.Info("info message")
+	logger.Error("error message")
+
+	if !strings.Contains(def.String(), "info message") || strings.Contains(def.String(), "error message") {
+		t.Fatalf("unexpected default output %q", def.String())
+	}
+	if !strings.Contains(errOut.String(), "error message") || strings.Contains(errOut.String(), "info message") {
+		t.Fatalf("unexpected error output %q", errOut.String())
+	}
+
+	_, _ = router.WriteLevel(logfactory.ERROR, []byte("direct\n"))
+	if !strings.Contains(errOut.String(), "direct") {
+		t.Fatal("expect router usable outside Logging")
+	}
+}
+
+func TestConditional(t *testing.T) {
+	buf := &bytes.Buffer{}
+	w := writer.NewConditional(buf, writer.MinLevel(logfactory.WARN))
+
+	logging := logfactory.NewLogging()
+	logging.SetOutput(writer.NewTee(w))
+	logger := logfactory.NewFactory(logging).GetLogger()
+	logger.Info("info message")
+	logger.Warn("warn message")
+
+	if strings.Contains(buf.String(), "info message") || !strings.Contains(buf.String(), "warn message") {
+		t.Fatalf("unexpected output %q", buf.String())
+	}
+
+	buf.Reset()
+	w = writer.NewConditional(buf, func(level int32, data []byte) bool {
+		return bytes.Contains(data, []byte("keep"))
+	})
+	_, _ = w.Write([]byte("drop\n"))
+	_, _ = w.Write([]byte("keep\n"))
+	if buf.String() != "keep\n" {
+		t.Fatalf("unexpected output %q", buf.String())
+	}
+}
+
+func TestRecordConditional(t *testing.T) {
+	audit := &bytes.Buffer{}
+	mem := appender.NewMemoryAppender(10, nil)
+	w := writer.NewTee(mem, writer.NewRecordConditional(audit, func(level int32, record util.KeyValues, data []byte) bool {
+		return record != nil && record.Get("audit") == true
+	}))
+
+	logging := logfactory.NewLogging()
+	logging.SetOutput(w)
+	logger := logfactory.NewFactory(logging).GetLogger()
+	logger.Info("normal")
+	logger.WithFields("audit", true).Info("audited")
+
+	if strings.Contains(audit.String(), "normal") || !strings.Contains(audit.String(), "audited") {
+		t.Fatalf("expect routed by record, but get %q", audit.String())
+	}
+	// 组合Writer中的RecordWriter仍可获得结构化日志记录
+	records := mem.Records(appender.Query{})
+	if len(records) != 2 || records[1].KeyValues.Get("audit") != true {
+		t.Fatalf("expect records kept through Tee, but get %+v", records)
+	}
+
+	defer func() {
+		if v := recover(); v != writer.ErrNilPredicate {
+			t.Fatalf("expect ErrNilPredicate panic, but get %v", v)
+		}
+	}()
+	writer.NewConditional(audit, nil)
+}
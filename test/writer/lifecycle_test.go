@@ -0,0 +1,141 @@
+/*
+ * Copyright (c) 2022, AcmeStack
+ * All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package writer
+
+import (
+	"bytes"
+	"context"
+	"github.com/acmestack/log4go/logfactory"
+	"github.com/acmestack/log4go/writer"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type syncBuffer struct {
+	lock   sync.Mutex
+	buf    bytes.Buffer
+	closed bool
+}
+
+func (b *syncBuffer) Write(d []byte) (int, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.buf.Write(d)
+}
+
+func (b *syncBuffer) Close() error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.closed = true
+	return nil
+}
+
+func (b *syncBuffer) String() string {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.buf.String()
+}
+
+func TestLoggingFlushAndClose(t *testing.T) {
+	buf := &syncBuffer{}
+	w := writer.NewAsyncBufferWriter(buf, buf.Close, writer.Config{
+		FlushSize:     10240,
+		BufferSize:    10,
+		FlushInterval: time.Hour,
+		Block:         true,
+	})
+	logging := logfactory.NewLogging()
+	logging.SetOutput(w)
+	fac := logfactory.NewFactory(logging)
+
+	fac.GetLogger().Info("flush test")
+	if err := fac.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "flush test") {
+		t.Fatalf("expect log flushed, but get %q", buf.String())
+	}
+
+	fac.GetLogger().Info("close test")
+	if err := fac.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "close test") || !buf.closed {
+		t.Fatalf("expect log flushed and writer closed, but get %q", buf.String())
+	}
+}
+
+func TestLoggingFlushBeforeFatal(t *testing.T) {
+	buf := &syncBuffer{}
+	w := writer.NewAsyncWriter(buf, nil, 10, true)
+	defer w.Close()
+
+	var output string
+	logging := logfactory.NewLogging(
+		logfactory.SetFatalNoTrace(true),
+		logfactory.SetExitFunc(func(code int) {
+			output = buf.String()
+		}))
+	logging.SetOutput(w)
+
+	logfactory.NewFactory(logging).GetLogger().Fatal("fatal test")
+	if !strings.Contains(output, "fatal test") {
+		t.Fatalf("expect fatal log flushed before exit, but get %q", output)
+	}
+}
+
+type countCloser struct {
+	syncBuffer
+	closes int
+}
+
+func (c *countCloser) Close() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.closes++
+	return nil
+}
+
+func TestLoggingRejectAfterClose(t *testing.T) {
+	w := &countCloser{}
+	var errs []error
+	logging := logfactory.NewLogging(
+		logfactory.SetFallbackWriter(w),
+		logfactory.SetErrorHandler(func(level logfactory.Level, err error) {
+			errs = append(errs, err)
+		}))
+	logging.SetOutput(w)
+	logger := logfactory.NewFactory(logging).GetLogger()
+
+	if err := logging.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if w.closes != 1 {
+		t.Fatalf("expect fallback writer closed once, but closed %d times", w.closes)
+	}
+
+	logger.Info("after close")
+	if w.String() != "" {
+		t.Fatalf("expect no write after close, but get %q", w.String())
+	}
+	if logging.FailedWrites() != 1 || len(errs) != 1 || errs[0] != logfactory.ErrLoggingClosed {
+		t.Fatalf("expect ErrLoggingClosed, but get %v, failed writes %d", errs, logging.FailedWrites())
+	}
+}
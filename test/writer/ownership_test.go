@@ -0,0 +1,103 @@
+/*
+ * Copyright (c) 2022, AcmeStack
+ * All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package writer
+
+import (
+	"github.com/acmestack/log4go/writer"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// 每个协程复用同一个buffer写入，Write返回后立即覆盖buffer，
+// 如果Writer没有拷贝数据，则输出会被破坏（使用-race运行时会报告数据竞争）
+func writeReusedBuffer(t *testing.T, w io.Writer, routines, count int) {
+	wait := sync.WaitGroup{}
+	wait.Add(routines)
+	for i := 0; i < routines; i++ {
+		go func(c byte) {
+			defer wait.Done()
+			buf := make([]byte, 33)
+			for j := 0; j < count; j++ {
+				for k := 0; k < len(buf)-1; k++ {
+					buf[k] = c
+				}
+				buf[len(buf)-1] = '\n'
+				_, _ = w.Write(buf)
+				for k := range buf {
+					buf[k] = 'X'
+				}
+			}
+		}(byte('a' + i))
+	}
+	wait.Wait()
+}
+
+func checkNoAliasing(t *testing.T, output string, expect int) {
+	lines := strings.Split(strings.TrimSuffix(output, "\n"), "\n")
+	if len(lines) != expect {
+		t.Fatalf("expect %d lines but get %d", expect, len(lines))
+	}
+	for _, line := range lines {
+		if len(line) != 32 || strings.Count(line, line[:1]) != 32 || line[0] == 'X' {
+			t.Fatalf("output corrupted: %q", line)
+		}
+	}
+}
+
+func TestAsyncWriterBufferOwnership(t *testing.T) {
+	buf := &syncBuffer{}
+	w := writer.NewAsyncWriter(buf, nil, 100, true)
+	writeReusedBuffer(t, w, 10, 100)
+	_ = w.Close()
+	checkNoAliasing(t, buf.String(), 1000)
+}
+
+func TestAsyncBufferWriterBufferOwnership(t *testing.T) {
+	buf := &syncBuffer{}
+	w := writer.NewAsyncBufferWriter(buf, nil, writer.Config{
+		FlushSize:     1024,
+		BufferSize:    100,
+		FlushInterval: time.Millisecond,
+		Block:         true,
+	})
+	writeReusedBuffer(t, w, 10, 100)
+	_ = w.Close()
+	checkNoAliasing(t, buf.String(), 1000)
+}
+
+func TestBufferedRotateFileBufferOwnership(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.log")
+	w := writer.NewBufferedRotateFileWriter(&writer.BufferedRotateFile{Path: path}, writer.Config{
+		FlushSize:     1024,
+		BufferSize:    100,
+		FlushInterval: time.Millisecond,
+		Block:         true,
+	})
+	writeReusedBuffer(t, w, 10, 100)
+	_ = w.Close()
+	d, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkNoAliasing(t, string(d), 1000)
+}
@@ -0,0 +1,125 @@
+/*
+ * Copyright (c) 2022, AcmeStack
+ * All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package writer
+
+import (
+	"github.com/acmestack/log4go/writer"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRotateFileReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.log")
+	f := &writer.RotateFile{Path: path}
+	if err := f.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	_, _ = f.Write([]byte("before\n"))
+	if err := os.Rename(path, path+".1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Reopen(); err != nil {
+		t.Fatal(err)
+	}
+	_, _ = f.Write([]byte("after\n"))
+
+	checkFile(t, path+".1", "before\n")
+	checkFile(t, path, "after\n")
+}
+
+func TestFileReopenCheck(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.log")
+	f := &writer.File{Path: path, ReopenCheckInterval: time.Millisecond}
+	if err := f.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	_, _ = f.Write([]byte("before\n"))
+	if err := os.Rename(path, path+".1"); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(5 * time.Millisecond)
+	_, _ = f.Write([]byte("after\n"))
+
+	checkFile(t, path+".1", "before\n")
+	checkFile(t, path, "after\n")
+}
+
+func TestBufferedRotateFileReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.log")
+	f := &writer.BufferedRotateFile{Path: path}
+	w := writer.NewBufferedRotateFileWriter(f, writer.Config{
+		FlushSize:     1024,
+		BufferSize:    10,
+		FlushInterval: time.Hour,
+		Block:         true,
+	})
+
+	_, _ = w.Write([]byte("before\n"))
+	time.Sleep(10 * time.Millisecond)
+	if err := os.Rename(path, path+".1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Reopen(); err != nil {
+		t.Fatal(err)
+	}
+	_, _ = w.Write([]byte("after\n"))
+	_ = w.Close()
+
+	checkFile(t, path+".1", "before\n")
+	checkFile(t, path, "after\n")
+}
+
+func TestAsyncBufferWriterReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.log")
+	w := writer.NewFileWriter(&writer.File{Path: path}, writer.Config{
+		FlushSize:     1024,
+		BufferSize:    10,
+		FlushInterval: time.Hour,
+		Block:         true,
+	})
+
+	_, _ = w.Write([]byte("before\n"))
+	time.Sleep(10 * time.Millisecond)
+	if err := os.Rename(path, path+".1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.(writer.Reopener).Reopen(); err != nil {
+		t.Fatal(err)
+	}
+	_, _ = w.Write([]byte("after\n"))
+	_ = w.Close()
+
+	checkFile(t, path+".1", "before\n")
+	checkFile(t, path, "after\n")
+}
+
+func checkFile(t *testing.T, path string, expect string) {
+	d, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(d) != expect {
+		t.Fatalf("%s: expect %q but get %q", path, expect, string(d))
+	}
+}
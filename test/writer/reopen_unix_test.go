@@ -0,0 +1,108 @@
+//go:build !windows
+
+/*
+ * Copyright (c) 2022, AcmeStack
+ * All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package writer
+
+import (
+	"github.com/acmestack/log4go/writer"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestReopenOnSignal(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.log")
+	f := &writer.File{Path: path}
+	if err := f.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	errChan := make(chan error, 1)
+	stop := writer.ReopenOnSignalWithHandler([]os.Signal{syscall.SIGHUP}, func(r writer.Reopener, err error) {
+		errChan <- err
+	}, f)
+	defer stop()
+
+	_, _ = f.Write([]byte("before\n"))
+	if err := os.Rename(path, path+".1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := syscall.Kill(os.Getpid(), syscall.SIGHUP); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-errChan:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("reopen not triggered by signal")
+	}
+	// 重新打开后即使没有写入也已创建新文件
+	checkFile(t, path, "")
+	_, _ = f.Write([]byte("after\n"))
+
+	checkFile(t, path+".1", "before\n")
+	checkFile(t, path, "after\n")
+}
+
+func TestReopenError(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "logs")
+	path := filepath.Join(dir, "test.log")
+	f := &writer.File{Path: path}
+	if err := f.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	if err := os.Rename(dir, dir+".1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Reopen(); err == nil {
+		t.Fatal("expect reopen error when directory is missing")
+	}
+	// 打开失败时继续写入原文件
+	if _, err := f.Write([]byte("still\n")); err != nil {
+		t.Fatal(err)
+	}
+	checkFile(t, filepath.Join(dir+".1", "test.log"), "still\n")
+}
+
+func TestReopenCheckError(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "logs")
+	path := filepath.Join(dir, "test.log")
+	f := &writer.RotateFile{Path: path, ReopenCheckInterval: time.Millisecond}
+	if err := f.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	if err := os.Rename(dir, dir+".1"); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(5 * time.Millisecond)
+	// 自动重新打开失败时返回错误，但仍写入原文件
+	if n, err := f.Write([]byte("still\n")); err == nil || n != len("still\n") {
+		t.Fatalf("expect record written and reopen error reported, but get %d %v", n, err)
+	}
+	checkFile(t, filepath.Join(dir+".1", "test.log"), "still\n")
+}
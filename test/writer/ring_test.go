@@ -0,0 +1,224 @@
+/*
+ * Copyright (c) 2022, AcmeStack
+ * All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package writer
+
+import (
+	"errors"
+	"github.com/acmestack/log4go/writer"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRingBufferWriter(t *testing.T) {
+	buf := &syncBuffer{}
+	w := writer.NewRingBufferWriter(buf, nil, writer.RingConfig{
+		Slots:     16,
+		SlotSize:  8,
+		BatchSize: 256,
+	})
+	writeReusedBuffer(t, w, 10, 200)
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	checkNoAliasing(t, buf.String(), 2000)
+	_ = w.Close()
+
+	stats := w.Stats()
+	if stats.Enqueued != 2000 || stats.Written != 2000 || stats.Dropped != 0 {
+		t.Fatalf("expect all data written, stats: %+v", stats)
+	}
+	if _, err := w.Write([]byte("test\n")); !errors.Is(err, writer.ErrWriterClosed) {
+		t.Fatalf("expect ErrWriterClosed but get %v", err)
+	}
+}
+
+func TestRingBufferWriterDrainOnClose(t *testing.T) {
+	sw := &slowWriter{delay: time.Millisecond}
+	w := writer.NewRingBufferWriter(sw, nil, writer.RingConfig{
+		Slots:     64,
+		BatchSize: 5,
+	})
+	for i := 0; i < 50; i++ {
+		_, _ = w.Write([]byte("test\n"))
+	}
+	_ = w.Close()
+	if sw.count != 50 || w.Stats().Written != 50 {
+		t.Fatalf("expect all data written, count: %d stats: %+v", sw.count, w.Stats())
+	}
+}
+
+func TestRingBufferWriterOverflow(t *testing.T) {
+	sw := &slowWriter{delay: 20 * time.Millisecond}
+	w := writer.NewRingBufferWriter(sw, nil, writer.RingConfig{
+		Slots:        2,
+		BatchSize:    5,
+		Policy:       writer.PolicyDropNewest,
+		DrainTimeout: -1,
+	})
+	var dropped int
+	for i := 0; i < 20; i++ {
+		if _, err := w.Write([]byte("test\n")); errors.Is(err, writer.ErrDropped) {
+			dropped++
+		}
+	}
+	_ = w.Close()
+	if dropped == 0 || w.Stats().Enqueued+uint64(dropped) != 20 {
+		t.Fatalf("expect data dropped, dropped: %d stats: %+v", dropped, w.Stats())
+	}
+}
+
+func TestRingBufferWriterLargeRecord(t *testing.T) {
+	buf := &syncBuffer{}
+	w := writer.NewRingBufferWriter(buf, nil, writer.RingConfig{
+		Slots:     4,
+		SlotSize:  8,
+		BatchSize: 16,
+	})
+	line := strings.Repeat("a", 100) + "\n"
+	for i := 0; i < 10; i++ {
+		_, _ = w.Write([]byte(line))
+	}
+	_ = w.Close()
+	if buf.String() != strings.Repeat(line, 10) {
+		t.Fatalf("output corrupted: %q", buf.String())
+	}
+}
+
+func TestRingBufferWriterCloseWhileWriting(t *testing.T) {
+	for i := 0; i < 20; i++ {
+		w := writer.NewRingBufferWriter(&slowWriter{}, nil, writer.RingConfig{
+			Slots:  4,
+			Policy: writer.PolicyBlock,
+		})
+		var accepted uint64
+		wait := sync.WaitGroup{}
+		for j := 0; j < 8; j++ {
+			wait.Add(1)
+			go func() {
+				defer wait.Done()
+				for k := 0; k < 100; k++ {
+					if _, err := w.Write([]byte("test\n")); err == nil {
+						atomic.AddUint64(&accepted, 1)
+					}
+				}
+			}()
+		}
+		time.Sleep(time.Millisecond)
+		_ = w.Close()
+		wait.Wait()
+
+		stats := w.Stats()
+		if stats.Enqueued != atomic.LoadUint64(&accepted) || stats.Written+stats.Dropped != stats.Enqueued {
+			t.Fatalf("expect every accepted record written or dropped, accepted: %d stats: %+v", accepted, stats)
+		}
+	}
+}
+
+func TestRingBufferWriterDropOldest(t *testing.T) {
+	sw := &slowWriter{delay: 5 * time.Millisecond}
+	w := writer.NewRingBufferWriter(sw, nil, writer.RingConfig{
+		Slots:     2,
+		BatchSize: 5,
+		Policy:    writer.PolicyDropOldest,
+	})
+	for i := 0; i < 20; i++ {
+		if _, err := w.Write([]byte("test\n")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	_ = w.Close()
+	stats := w.Stats()
+	if stats.Enqueued != 20 || stats.Dropped == 0 || stats.Written+stats.Dropped != 20 {
+		t.Fatalf("expect oldest data dropped, stats: %+v", stats)
+	}
+}
+
+type flushBuffer struct {
+	syncBuffer
+	flushes int32
+}
+
+func (b *flushBuffer) Flush() error {
+	atomic.AddInt32(&b.flushes, 1)
+	return nil
+}
+
+func TestRingBufferWriterFlushWrapped(t *testing.T) {
+	buf := &flushBuffer{}
+	w := writer.NewRingBufferWriter(buf, nil)
+	defer w.Close()
+
+	_, _ = w.Write([]byte("test\n"))
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "test\n" || atomic.LoadInt32(&buf.flushes) != 1 {
+		t.Fatalf("expect data written and wrapped writer flushed, get %q flushes %d", buf.String(), buf.flushes)
+	}
+}
+
+// gateWriter 第一次写入阻塞直到gate关闭，记录写入次数
+type gateWriter struct {
+	syncBuffer
+	gate   chan struct{}
+	writes int32
+}
+
+func (w *gateWriter) Write(p []byte) (int, error) {
+	if atomic.AddInt32(&w.writes, 1) == 1 {
+		<-w.gate
+	}
+	return w.syncBuffer.Write(p)
+}
+
+func TestRingBufferWriterCoalesce(t *testing.T) {
+	gw := &gateWriter{gate: make(chan struct{})}
+	w := writer.NewRingBufferWriter(gw, nil, writer.RingConfig{
+		Slots:  4,
+		Policy: writer.PolicyBlock,
+	})
+	_, _ = w.Write([]byte("first\n"))
+	for atomic.LoadInt32(&gw.writes) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// 实际Writer阻塞时队列已满，写入者阻塞等待，不丢弃数据
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 12; i++ {
+			_, _ = w.Write([]byte("test\n"))
+		}
+	}()
+	time.Sleep(20 * time.Millisecond)
+	close(gw.gate)
+	<-done
+	_ = w.Close()
+
+	stats := w.Stats()
+	if stats.Written != 13 || stats.Dropped != 0 || gw.String() != "first\n"+strings.Repeat("test\n", 12) {
+		t.Fatalf("expect all data written, stats: %+v output: %q", stats, gw.String())
+	}
+	// 每批合并为一次写入
+	if n := atomic.LoadInt32(&gw.writes); n >= 13 {
+		t.Fatalf("expect batched writes, but get %d writes", n)
+	}
+}
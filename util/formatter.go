@@ -23,6 +23,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"strings"
 	"time"
 )
 
@@ -33,14 +34,19 @@ type Iterator interface {
 
 type KeyValues interface {
 	Add(keyAndValues ...interface{}) error
+	// GetAll 获得所有键值的副本
 	GetAll() map[string]interface{}
+	// Keys 按添加顺序获得所有键的副本，修改返回值不影响KeyValues
 	Keys() []string
 	Get(key string) interface{}
 	Remove(key string) error
 	Len() int
 
 	Iterator() Iterator
+	// Range 按添加顺序遍历键值，f返回false时停止，不产生内存分配
+	Range(f func(key string, value interface{}) bool)
 
+	// Clone 获得副本，副本与原KeyValues共享已有的键值，修改时才复制
 	Clone() KeyValues
 }
 
@@ -49,136 +55,109 @@ type Formatter interface {
 	Format(writer io.Writer, keyValues KeyValues) error
 }
 
-type defaultKeyValues [2]interface{}
-
-func NewKeyValues(keyAndValues ...interface{}) KeyValues {
-	ret := &defaultKeyValues{}
-	ret[0] = []string{}
-	ret[1] = map[string]interface{}{}
+// KeyCollision 合并附加信息时键冲突的处理策略
+type KeyCollision int
+
+const (
+	// CollisionRename 在冲突的键后添加后缀（默认DefaultCollisionSuffix），默认策略
+	CollisionRename KeyCollision = iota
+	// CollisionPrefix 在所有合并的键前添加前缀（默认DefaultCollisionPrefix），添加后仍冲突时重复添加
+	CollisionPrefix
+	// CollisionReject 丢弃冲突的键值
+	CollisionReject
+	// CollisionOverwrite 覆盖已有键的值
+	CollisionOverwrite
+)
 
-	_ = ret.Add(keyAndValues...)
-	return ret
-}
+const (
+	// DefaultCollisionSuffix CollisionRename默认的后缀
+	DefaultCollisionSuffix = "_"
+	// DefaultCollisionPrefix CollisionPrefix默认的前缀
+	DefaultCollisionPrefix = "fields."
+)
 
-func (f *defaultKeyValues) Add(keyAndValues ...interface{}) error {
-	size := len(keyAndValues)
-	if size == 0 {
-		return nil
-	}
-	//keys := f[0].([]string)
-	kvs := f[1].(map[string]interface{})
-	var k string
-	for i := 0; i < size; i++ {
-		if i%2 == 0 {
-			if keyAndValues[i] == nil {
-				return errors.New("Key must be not nil ")
-			}
-			s, ok := keyAndValues[i].(string)
-			if !ok {
-				return errors.New("Key must be string ")
-			}
-			k = s
-			if _, ok := kvs[k]; !ok {
-				f[0] = append(f[0].([]string), k)
-			}
-		} else {
-			kvs[k] = keyAndValues[i]
+// ErrKeyCollision CollisionReject策略丢弃键值时的错误
+var ErrKeyCollision = errors.New("Field key collides with builtin key ")
+
+// ResolveKey 获得与已有键冲突的key最终使用的键，affix为CollisionPrefix的前缀或CollisionRename的后缀，为空时使用默认值，
+// exists判断键是否已被占用，重命名后仍冲突时重复添加前缀或后缀。返回false时丢弃该键值（CollisionReject）
+func (c KeyCollision) ResolveKey(key, affix string, exists func(key string) bool) (string, bool) {
+	switch c {
+	case CollisionOverwrite:
+		return key, true
+	case CollisionReject:
+		return "", false
+	case CollisionPrefix:
+		if affix == "" {
+			affix = DefaultCollisionPrefix
 		}
-	}
-	return nil
-}
-
-func (f defaultKeyValues) GetAll() map[string]interface{} {
-	return f[1].(map[string]interface{})
-}
-
-func (f defaultKeyValues) Iterator() Iterator {
-	return &defaultIterator{
-		keyValues: f,
-		cur:       0,
-	}
-}
-
-func (f defaultKeyValues) Keys() []string {
-	return f[0].([]string)
-}
-
-func (f defaultKeyValues) Get(key string) interface{} {
-	return f[1].(map[string]interface{})[key]
-}
-
-func (f *defaultKeyValues) Remove(key string) error {
-	_, ok := f[1].(map[string]interface{})[key]
-	if ok {
-		delete(f[1].(map[string]interface{}), key)
-		keys := f[0].([]string)
-		for i := 0; i < len(keys); i++ {
-			if keys[i] == key {
-				f[0] = append(keys[:i], keys[i+1:]...)
-				break
-			}
+		key = affix + key
+		for exists(key) {
+			key = affix + key
+		}
+	default:
+		if affix == "" {
+			affix = DefaultCollisionSuffix
+		}
+		key = key + affix
+		for exists(key) {
+			key = key + affix
 		}
-		return nil
-	} else {
-		return errors.New("Key not found ")
-	}
-}
-
-func (f defaultKeyValues) Len() int {
-	return len(f[0].([]string))
-}
-
-func (f defaultKeyValues) Clone() KeyValues {
-	ret := &defaultKeyValues{}
-	keys := make([]string, len(f[0].([]string)))
-	kvs := make(map[string]interface{}, len(f[1].(map[string]interface{})))
-
-	copy(keys, f[0].([]string))
-	for _, k := range keys {
-		kvs[k] = f[1].(map[string]interface{})[k]
 	}
-	ret[0] = keys
-	ret[1] = kvs
-
-	return ret
+	return key, true
 }
 
-type defaultIterator struct {
-	keyValues defaultKeyValues
-	cur       int
-}
-
-func (c *defaultIterator) HasNext() bool {
-	return c.cur < len(c.keyValues[0].([]string))
-}
-
-func (c *defaultIterator) Next() (string, interface{}) {
-	v := c.keyValues[0].([]string)[c.cur]
-	c.cur++
-	return v, c.keyValues[1].(map[string]interface{})[v]
+// MergeKeyValues 将其余的KeyValues合并到第一个KeyValues中，键已存在时添加后缀（CollisionRename）
+func MergeKeyValues(keyValues ...KeyValues) (KeyValues, error) {
+	return MergeKeyValuesWithCollision(CollisionRename, "", keyValues...)
 }
 
-func MergeKeyValues(keyValues ...KeyValues) (KeyValues, error) {
+// MergeKeyValuesWithCollision 将其余的KeyValues合并到第一个KeyValues中，键与第一个KeyValues原有的键冲突时按policy处理，
+// affix同KeyCollision.ResolveKey。CollisionPrefix时所有合并的键均添加前缀，
+// CollisionReject时跳过冲突的键值，合并完成后返回ErrKeyCollision
+func MergeKeyValuesWithCollision(policy KeyCollision, affix string, keyValues ...KeyValues) (KeyValues, error) {
 	if len(keyValues) == 0 {
 		return nil, errors.New("No keyValues to merge ")
-	} else {
-		kvs := keyValues[0]
-		var tmp KeyValues
-		for i := 1; i < len(keyValues); i++ {
-			tmp = keyValues[i]
-			if tmp == nil {
-				continue
-			}
-			keys := tmp.Keys()
-			for _, k := range keys {
-				err := kvs.Add(k, tmp.Get(k))
-				if err != nil {
-					return kvs, err
+	}
+	kvs := keyValues[0]
+	builtin := map[string]struct{}{}
+	for _, k := range kvs.Keys() {
+		builtin[k] = struct{}{}
+	}
+	exists := func(key string) bool {
+		if _, ok := builtin[key]; ok {
+			return true
+		}
+		found := false
+		kvs.Range(func(k string, _ interface{}) bool {
+			found = k == key
+			return !found
+		})
+		return found
+	}
+	var rejected []string
+	for i := 1; i < len(keyValues); i++ {
+		tmp := keyValues[i]
+		if tmp == nil {
+			continue
+		}
+		for _, k := range tmp.Keys() {
+			key := k
+			if _, ok := builtin[k]; ok || policy == CollisionPrefix {
+				if key, ok = policy.ResolveKey(k, affix, exists); !ok {
+					rejected = append(rejected, k)
+					continue
 				}
 			}
+			if err := kvs.Add(key, tmp.Get(k)); err != nil {
+				return kvs, err
+			}
 		}
-		return kvs, nil
 	}
+	if len(rejected) > 0 {
+		return kvs, fmt.Errorf("%w: %s", ErrKeyCollision, strings.Join(rejected, ", "))
+	}
+	return kvs, nil
 }
 
 type TextFormatter struct {
@@ -188,21 +167,22 @@ type TextFormatter struct {
 }
 
 func (f *TextFormatter) Format(writer io.Writer, keyValues KeyValues) error {
-	keys := keyValues.Keys()
-	if len(keys) == 0 {
+	if keyValues.Len() == 0 {
 		return nil
 	}
 
+	buf := bytes.Buffer{}
 	if f.SortFunc != nil {
+		keys := keyValues.Keys()
 		f.SortFunc(keys)
-	}
-
-	buf := bytes.Buffer{}
-	for _, k := range keys {
-		buf.WriteString(k)
-		buf.WriteByte('=')
-		buf.WriteString(f.formatValue(keyValues.Get(k)))
-		buf.WriteByte(' ')
+		for _, k := range keys {
+			f.formatField(&buf, k, keyValues.Get(k))
+		}
+	} else {
+		keyValues.Range(func(key string, value interface{}) bool {
+			f.formatField(&buf, key, value)
+			return true
+		})
 	}
 	if buf.Cap() == 0 || buf.Bytes()[buf.Len()-1] != '\n' {
 		buf.WriteByte('\n')
@@ -211,11 +191,19 @@ func (f *TextFormatter) Format(writer io.Writer, keyValues KeyValues) error {
 	return err
 }
 
+func (f *TextFormatter) formatField(buf *bytes.Buffer, key string, value interface{}) {
+	buf.WriteString(key)
+	buf.WriteByte('=')
+	buf.WriteString(f.formatValue(value))
+	buf.WriteByte(' ')
+}
+
 func (f *TextFormatter) formatValue(o interface{}) string {
 	if o == nil {
 		return ""
 	}
 
+	o = ResolveValue(o)
 	if t, ok := o.(time.Time); ok {
 		if f.TimeFormat != nil {
 			o = f.TimeFormat(t)
@@ -242,14 +230,75 @@ func FormatValue(o interface{}, quote bool) string {
 	return ret
 }
 
+// GroupSeparator 分组（Logger.WithGroup）与键之间的分隔符
+const GroupSeparator = "."
+
 type JsonFormatter struct {
+	// Nested 为true时按GroupSeparator将键拆分为嵌套的JSON对象，如http.status输出为{"http":{"status":200}}，
+	// 与已有的非对象值冲突时保留原始的键
+	Nested bool
 }
 
 func (f *JsonFormatter) Format(writer io.Writer, keyValues KeyValues) error {
-	d, err := json.Marshal(keyValues.GetAll())
+	values := keyValues.GetAll()
+	for _, v := range values {
+		if _, ok := v.(LogValuer); ok {
+			values = resolveValues(values)
+			break
+		}
+	}
+	if f.Nested {
+		values = nestValues(keyValues.Keys(), values)
+	}
+	d, err := json.Marshal(values)
 	if err != nil {
 		return err
 	}
 	_, err = writer.Write(d)
 	return err
 }
+
+// resolveValues 返回解析了LogValuer的副本
+func resolveValues(values map[string]interface{}) map[string]interface{} {
+	ret := make(map[string]interface{}, len(values))
+	for k, v := range values {
+		ret[k] = ResolveValue(v)
+	}
+	return ret
+}
+
+// nestValues 按GroupSeparator将键拆分为嵌套的map，不含分隔符的键优先，其余按keys的顺序处理
+func nestValues(keys []string, values map[string]interface{}) map[string]interface{} {
+	ret := make(map[string]interface{}, len(values))
+	for _, k := range keys {
+		if !strings.Contains(k, GroupSeparator) {
+			ret[k] = values[k]
+		}
+	}
+	for _, k := range keys {
+		if !strings.Contains(k, GroupSeparator) {
+			continue
+		}
+		parts := strings.Split(k, GroupSeparator)
+		m := ret
+		for _, p := range parts[:len(parts)-1] {
+			sub, ok := m[p].(map[string]interface{})
+			if !ok {
+				if _, exist := m[p]; exist {
+					m = nil
+					break
+				}
+				sub = map[string]interface{}{}
+				m[p] = sub
+			}
+			m = sub
+		}
+		leaf := parts[len(parts)-1]
+		if _, exist := m[leaf]; m == nil || exist {
+			ret[k] = values[k]
+		} else {
+			m[leaf] = values[k]
+		}
+	}
+	return ret
+}
@@ -0,0 +1,200 @@
+/*
+ * Copyright (c) 2022, AcmeStack
+ * All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package util
+
+import (
+	"errors"
+	"sync/atomic"
+)
+
+type kvEntry struct {
+	key   string
+	value interface{}
+}
+
+// kvArray 记录多个defaultKeyValues共享的底层数组的使用情况
+type kvArray struct {
+	// used 已被写入的长度，只有长度等于used的持有者可以原地追加
+	used int32
+	// frozen 被Clone共享的长度，修改小于该长度的元素前需复制
+	frozen int32
+}
+
+// defaultKeyValues 写时复制的KeyValues，Clone不复制键值，与原KeyValues共享底层数组，
+// 原地追加通过kvArray.used保证同一位置只被一个持有者写入，因此派生Logger的附加信息可以廉价地共享父Logger的附加信息。
+// 键值数量通常较少，查找使用顺序遍历
+type defaultKeyValues struct {
+	entries []kvEntry
+	array   *kvArray
+}
+
+func NewKeyValues(keyAndValues ...interface{}) KeyValues {
+	ret := &defaultKeyValues{
+		entries: make([]kvEntry, 0, (len(keyAndValues)+1)/2),
+		array:   &kvArray{},
+	}
+	_ = ret.Add(keyAndValues...)
+	return ret
+}
+
+func (f *defaultKeyValues) Add(keyAndValues ...interface{}) error {
+	for i := 0; i < len(keyAndValues); i += 2 {
+		if keyAndValues[i] == nil {
+			return errors.New("Key must be not nil ")
+		}
+		k, ok := keyAndValues[i].(string)
+		if !ok {
+			return errors.New("Key must be string ")
+		}
+		var v interface{}
+		if i+1 < len(keyAndValues) {
+			v = keyAndValues[i+1]
+		}
+		f.set(k, v)
+	}
+	return nil
+}
+
+func (f *defaultKeyValues) set(key string, value interface{}) {
+	if i := f.index(key); i >= 0 {
+		if i < int(atomic.LoadInt32(&f.array.frozen)) {
+			f.copyOnWrite(0)
+		}
+		f.entries[i].value = value
+		return
+	}
+
+	n := len(f.entries)
+	if n < cap(f.entries) && atomic.CompareAndSwapInt32(&f.array.used, int32(n), int32(n+1)) {
+		f.entries = f.entries[:n+1]
+	} else {
+		f.copyOnWrite(1)
+		f.entries = f.entries[:n+1]
+	}
+	f.entries[n] = kvEntry{key: key, value: value}
+}
+
+// copyOnWrite 复制键值到新的底层数组，并预留至少grow个位置
+func (f *defaultKeyValues) copyOnWrite(grow int) {
+	n := len(f.entries)
+	size := n + grow
+	if size < 2*n {
+		size = 2 * n
+	}
+	if size < 4 {
+		size = 4
+	}
+	entries := make([]kvEntry, n, size)
+	copy(entries, f.entries)
+	f.entries = entries
+	f.array = &kvArray{used: int32(n + grow)}
+}
+
+func (f *defaultKeyValues) index(key string) int {
+	for i := range f.entries {
+		if f.entries[i].key == key {
+			return i
+		}
+	}
+	return -1
+}
+
+func (f *defaultKeyValues) GetAll() map[string]interface{} {
+	ret := make(map[string]interface{}, len(f.entries))
+	for _, e := range f.entries {
+		ret[e.key] = e.value
+	}
+	return ret
+}
+
+func (f *defaultKeyValues) Iterator() Iterator {
+	return &defaultIterator{
+		entries: f.entries,
+	}
+}
+
+func (f *defaultKeyValues) Range(fn func(key string, value interface{}) bool) {
+	for _, e := range f.entries {
+		if !fn(e.key, e.value) {
+			return
+		}
+	}
+}
+
+func (f *defaultKeyValues) Keys() []string {
+	ret := make([]string, len(f.entries))
+	for i, e := range f.entries {
+		ret[i] = e.key
+	}
+	return ret
+}
+
+func (f *defaultKeyValues) Get(key string) interface{} {
+	if i := f.index(key); i >= 0 {
+		return f.entries[i].value
+	}
+	return nil
+}
+
+func (f *defaultKeyValues) Remove(key string) error {
+	i := f.index(key)
+	if i < 0 {
+		return errors.New("Key not found ")
+	}
+	if i < int(atomic.LoadInt32(&f.array.frozen)) {
+		f.copyOnWrite(0)
+	}
+	n := len(f.entries)
+	copy(f.entries[i:], f.entries[i+1:])
+	f.entries[n-1] = kvEntry{}
+	f.entries = f.entries[:n-1]
+	return nil
+}
+
+func (f *defaultKeyValues) Len() int {
+	return len(f.entries)
+}
+
+func (f *defaultKeyValues) Clone() KeyValues {
+	n := int32(len(f.entries))
+	for {
+		frozen := atomic.LoadInt32(&f.array.frozen)
+		if frozen >= n || atomic.CompareAndSwapInt32(&f.array.frozen, frozen, n) {
+			break
+		}
+	}
+	return &defaultKeyValues{
+		entries: f.entries,
+		array:   f.array,
+	}
+}
+
+type defaultIterator struct {
+	entries []kvEntry
+	cur     int
+}
+
+func (c *defaultIterator) HasNext() bool {
+	return c.cur < len(c.entries)
+}
+
+func (c *defaultIterator) Next() (string, interface{}) {
+	e := c.entries[c.cur]
+	c.cur++
+	return e.key, e.value
+}
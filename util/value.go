@@ -21,14 +21,18 @@ import (
 	"sync/atomic"
 )
 
-// Value 存储值对象工具，interface不做类型检查，需用户自行确认存取类型
-type Value interface {
+// TypedValue 存储值对象工具，T为存储值的类型
+type TypedValue[T any] interface {
 	// Store 存储值
-	Store(interface{})
+	Store(T)
 	// Load 取出值
-	Load() interface{}
+	Load() T
 }
 
+// Value 存储值对象工具，interface不做类型检查，需用户自行确认存取类型
+type Value = TypedValue[interface{}]
+
+// SimpleValue 非线程安全的Value，仅用于初始化后不再修改的值
 type SimpleValue struct {
 	o interface{}
 }
@@ -45,17 +49,35 @@ func (l *SimpleValue) Store(o interface{}) {
 	l.o = o
 }
 
-type AtomicValue atomic.Value
+// Atomic 线程安全的TypedValue，基于atomic.Pointer实现，Load无需类型断言及内存分配。
+// Store时会将v的副本分配到堆上（每次Store一次内存分配），适用于读多写少的配置
+type Atomic[T any] struct {
+	p atomic.Pointer[T]
+}
 
-func NewAtomicValue(o interface{}) *AtomicValue {
-	ret := &AtomicValue{}
-	ret.Store(o)
+// NewAtomic 创建存储v的Atomic
+func NewAtomic[T any](v T) *Atomic[T] {
+	ret := &Atomic[T]{}
+	ret.Store(v)
 	return ret
 }
-func (l *AtomicValue) Load() interface{} {
-	return (*atomic.Value)(l).Load().(*SimpleValue).Load()
+
+// Load 取出值，未存储时返回T的零值
+func (a *Atomic[T]) Load() T {
+	if p := a.p.Load(); p != nil {
+		return *p
+	}
+	var zero T
+	return zero
+}
+
+func (a *Atomic[T]) Store(v T) {
+	a.p.Store(&v)
 }
 
-func (l *AtomicValue) Store(o interface{}) {
-	(*atomic.Value)(l).Store(&SimpleValue{o: o})
+// AtomicValue 线程安全的Value
+type AtomicValue = Atomic[interface{}]
+
+func NewAtomicValue(o interface{}) *AtomicValue {
+	return NewAtomic[interface{}](o)
 }
@@ -0,0 +1,57 @@
+/*
+ * Copyright (c) 2022, AcmeStack
+ * All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package util
+
+import "fmt"
+
+// maxResolveDepth LogValue返回LogValuer时最多解析的次数
+const maxResolveDepth = 8
+
+// LogValuer 延迟计算日志输出值的类型，仅在日志确定输出时（级别检查之后）调用LogValue，
+// 作为日志参数或KeyValues的值时输出LogValue的返回值
+type LogValuer interface {
+	LogValue() interface{}
+}
+
+// Lazy 延迟计算的值，如：logger.Debug("state: ", util.Lazy(func() interface{} { return dump() }))
+type Lazy func() interface{}
+
+func (f Lazy) LogValue() interface{} {
+	return f()
+}
+
+// ResolveValue 获得LogValuer的输出值，LogValue发生panic时返回panic信息，v不是LogValuer时直接返回
+func ResolveValue(v interface{}) (ret interface{}) {
+	for i := 0; i < maxResolveDepth; i++ {
+		lv, ok := v.(LogValuer)
+		if !ok {
+			return v
+		}
+		v = resolveOnce(lv)
+	}
+	return v
+}
+
+func resolveOnce(lv LogValuer) (ret interface{}) {
+	defer func() {
+		if r := recover(); r != nil {
+			ret = fmt.Sprintf("!PANIC in LogValue: %v", r)
+		}
+	}()
+	return lv.LogValue()
+}
@@ -33,7 +33,8 @@ const (
 type AsyncBufferLogWriter struct {
 	wait      sync.WaitGroup
 	stopChan  chan bool
-	logChan   chan []byte
+	logChan   chan *[]byte
+	cmdChan   chan func()
 	logBuffer bytes.Buffer
 	FlushSize int64
 	w         io.Writer
@@ -81,7 +82,8 @@ func NewAsyncBufferWriter(w io.Writer, closer Closer, c ...Config) *AsyncBufferL
 
 	l := AsyncBufferLogWriter{
 		stopChan:  make(chan bool),
-		logChan:   make(chan []byte, conf.BufferSize),
+		logChan:   make(chan *[]byte, conf.BufferSize),
+		cmdChan:   make(chan func()),
 		FlushSize: conf.FlushSize,
 		w:         w,
 		block:     conf.Block,
@@ -96,7 +98,7 @@ func NewAsyncBufferWriter(w io.Writer, closer Closer, c ...Config) *AsyncBufferL
 			for i := 0; i < size; i++ {
 				_ = l.writeLog(<-l.logChan)
 			}
-			_ = l.Flush()
+			_ = l.flush()
 			if closer != nil {
 				_ = closer()
 			}
@@ -111,14 +113,16 @@ func NewAsyncBufferWriter(w io.Writer, closer Closer, c ...Config) *AsyncBufferL
 				if ok {
 					_ = l.writeLog(d)
 				}
+			case cmd := <-l.cmdChan:
+				cmd()
 			case <-ticker.C:
-				_ = l.Flush()
+				_ = l.flush()
 			}
 			select {
 			case <-l.stopChan:
 				return
 			case <-ticker.C:
-				_ = l.Flush()
+				_ = l.flush()
 			default:
 			}
 		}
@@ -126,7 +130,24 @@ func NewAsyncBufferWriter(w io.Writer, closer Closer, c ...Config) *AsyncBufferL
 	return &l
 }
 
+// Flush 将已接收及缓存的数据写入实际的Writer（线程安全），如果实际的Writer实现了Flusher则同时调用其Flush
 func (w *AsyncBufferLogWriter) Flush() error {
+	return w.exec(func() error {
+		size := len(w.logChan)
+		for i := 0; i < size; i++ {
+			_ = w.writeLog(<-w.logChan)
+		}
+		if err := w.flush(); err != nil {
+			return err
+		}
+		if f, ok := w.w.(Flusher); ok {
+			return f.Flush()
+		}
+		return nil
+	})
+}
+
+func (w *AsyncBufferLogWriter) flush() error {
 	d := w.logBuffer.Bytes()
 	if len(d) > 0 {
 		_, err := w.w.Write(d)
@@ -138,14 +159,38 @@ func (w *AsyncBufferLogWriter) Flush() error {
 	return nil
 }
 
-func (w *AsyncBufferLogWriter) writeLog(data []byte) error {
-	w.logBuffer.Write(data)
+func (w *AsyncBufferLogWriter) writeLog(p *[]byte) error {
+	w.logBuffer.Write(*p)
+	releaseBytes(p)
 
 	if int64(w.logBuffer.Len()) < w.FlushSize {
 		return nil
 	}
 
-	return w.Flush()
+	return w.flush()
+}
+
+// Reopen 将缓存写入后调用实际写入Writer的Reopen方法（线程安全），实际写入的Writer需实现Reopener
+func (w *AsyncBufferLogWriter) Reopen() error {
+	r, ok := w.w.(Reopener)
+	if !ok {
+		return errors.New("writer cannot reopen")
+	}
+	return w.exec(func() error {
+		_ = w.flush()
+		return r.Reopen()
+	})
+}
+
+// exec 在写入协程中执行f，保证与写入操作串行
+func (w *AsyncBufferLogWriter) exec(f func() error) error {
+	errChan := make(chan error, 1)
+	select {
+	case w.cmdChan <- func() { errChan <- f() }:
+		return <-errChan
+	case <-w.stopChan:
+		return ErrWriterClosed
+	}
 }
 
 func (w *AsyncBufferLogWriter) Close() error {
@@ -156,25 +201,30 @@ func (w *AsyncBufferLogWriter) Close() error {
 	return nil
 }
 
+// Write 将data的拷贝放入异步队列，调用者在返回后可以复用data
 func (w *AsyncBufferLogWriter) Write(data []byte) (n int, err error) {
 	if len(data) == 0 {
 		return 0, nil
 	}
+	p := copyBytes(data)
 	if w.block {
 		select {
-		case w.logChan <- data:
+		case w.logChan <- p:
 			return len(data), nil
 		case <-w.stopChan:
-			return 0, errors.New("writer is closed")
+			releaseBytes(p)
+			return 0, ErrWriterClosed
 		}
 	} else {
 		select {
-		case w.logChan <- data:
+		case w.logChan <- p:
 			return len(data), nil
 		case <-w.stopChan:
-			return 0, errors.New("writer is closed")
+			releaseBytes(p)
+			return 0, ErrWriterClosed
 		default:
-			return 0, errors.New("write log failed ")
+			releaseBytes(p)
+			return 0, ErrDropped
 		}
 	}
 }
@@ -21,38 +21,130 @@ import (
 	"errors"
 	"io"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 const (
 	BufferSize = 10240
+	DrainTime  = 3 * time.Second
+	// BlockTime PolicyBlockTimeout默认的最长阻塞时间
+	BlockTime = 100 * time.Millisecond
+)
+
+var (
+	// ErrWriterClosed Writer已关闭
+	ErrWriterClosed = errors.New("writer is closed")
+	// ErrDropped 队列已满，数据被丢弃
+	ErrDropped = errors.New("queue is full, data dropped")
 )
 
 type Closer func() error
 
+// Flusher 带缓存的Writer，Flush将缓存的数据写入实际的Writer
+type Flusher interface {
+	Flush() error
+}
+
+// DropPolicy 异步队列已满时的处理策略
+type DropPolicy int
+
+const (
+	// PolicyBlock 阻塞直到数据进入队列
+	PolicyBlock DropPolicy = iota
+	// PolicyDropNewest 丢弃当前写入的数据，Write返回ErrDropped
+	PolicyDropNewest
+	// PolicyDropOldest 丢弃队列中最早的数据，写入当前数据
+	PolicyDropOldest
+	// PolicyBlockTimeout 阻塞直到数据进入队列，超过BlockTimeout则丢弃当前写入的数据，Write返回ErrDropped
+	PolicyBlockTimeout
+)
+
+type AsyncConfig struct {
+	// 异步队列的大小，小于等于0时为无缓冲队列
+	BufferSize int
+
+	// 队列已满时的处理策略
+	Policy DropPolicy
+
+	// Policy为PolicyBlockTimeout时的最长阻塞时间，小于等于0时使用BlockTime
+	BlockTimeout time.Duration
+
+	// Close时等待队列中数据写入的超时时间，为0时使用DrainTime，小于0时不等待直接丢弃队列中的数据
+	DrainTimeout time.Duration
+
+	// 实际Writer写入失败时的回调，在写入协程中调用
+	OnError func(err error)
+}
+
+// AsyncStats 异步Writer的统计数据
+type AsyncStats struct {
+	// 进入队列的数量
+	Enqueued uint64
+	// 成功写入实际Writer的数量
+	Written uint64
+	// 被丢弃的数量（包括队列已满及Close时未能写入的数据）
+	Dropped uint64
+	// 实际Writer写入失败的数量
+	WriteErrors uint64
+}
+
 type AsyncLogWriter struct {
+	// stopChan 关闭时通知阻塞的Write返回，quitChan 关闭时通知写入协程写入剩余数据后退出
 	stopChan chan struct{}
-	logChan  chan []byte
-	w        io.Writer
-	block    bool
-	wait     sync.WaitGroup
-	once     sync.Once
+	quitChan chan struct{}
+	// lock Write持有读锁，Close持有写锁等待正在入队的Write完成，保证写入协程退出前不再有数据入队
+	lock    sync.RWMutex
+	closed  bool
+	logChan chan *[]byte
+	cmdChan chan func()
+	w       io.Writer
+	conf    AsyncConfig
+	wait    sync.WaitGroup
+	once    sync.Once
+
+	enqueued    uint64
+	written     uint64
+	dropped     uint64
+	writeErrors uint64
 }
 
 // 异步写的Writer，本身Write、Close方法线程安全，参数WriteCloser可以非线程安全
 // Param： w - 实际写入的Writer, bufSize - 接收的最大长度, block - 如果为true，则当超出bufSize大小时Write方法阻塞，否则返回error
 func NewAsyncWriter(w io.Writer, closer Closer, bufSize int, block bool) *AsyncLogWriter {
-	var logChan chan []byte
+	conf := AsyncConfig{
+		BufferSize: bufSize,
+		Policy:     PolicyDropNewest,
+	}
+	if block {
+		conf.Policy = PolicyBlock
+	}
+	return NewAsyncWriterWithConfig(w, closer, conf)
+}
+
+// 异步写的Writer，本身Write、Close方法线程安全，参数WriteCloser可以非线程安全
+// Param： w - 实际写入的Writer, closer - 写入协程退出时调用, conf - Writer的配置
+func NewAsyncWriterWithConfig(w io.Writer, closer Closer, conf AsyncConfig) *AsyncLogWriter {
+	var logChan chan *[]byte
 	// Channel without buffer
-	if bufSize <= 0 {
-		logChan = make(chan []byte)
+	if conf.BufferSize <= 0 {
+		logChan = make(chan *[]byte)
 	} else {
-		logChan = make(chan []byte, bufSize)
+		logChan = make(chan *[]byte, conf.BufferSize)
+	}
+	if conf.DrainTimeout == 0 {
+		conf.DrainTimeout = DrainTime
+	}
+	if conf.Policy == PolicyBlockTimeout && conf.BlockTimeout <= 0 {
+		conf.BlockTimeout = BlockTime
 	}
-	l := AsyncLogWriter{
+	l := &AsyncLogWriter{
 		stopChan: make(chan struct{}),
+		quitChan: make(chan struct{}),
 		logChan:  logChan,
+		cmdChan:  make(chan func()),
 		w:        w,
-		block:    block,
+		conf:     conf,
 	}
 	l.wait.Add(1)
 
@@ -63,46 +155,179 @@ func NewAsyncWriter(w io.Writer, closer Closer, bufSize int, block bool) *AsyncL
 		}
 		for {
 			select {
-			case <-l.stopChan:
+			case <-l.quitChan:
+				l.drain()
 				return
 			case d, ok := <-l.logChan:
 				if ok {
 					l.writeLog(d)
 				}
+			case cmd := <-l.cmdChan:
+				cmd()
 			}
 		}
 	}()
-	return &l
+	return l
+}
+
+// drain 在DrainTimeout内将队列中的数据写入，超时后剩余的数据计入丢弃数量
+func (w *AsyncLogWriter) drain() {
+	if w.conf.DrainTimeout > 0 {
+		deadline := time.Now().Add(w.conf.DrainTimeout)
+		for time.Now().Before(deadline) {
+			select {
+			case d := <-w.logChan:
+				w.writeLog(d)
+			default:
+				return
+			}
+		}
+	}
+	atomic.AddUint64(&w.dropped, uint64(len(w.logChan)))
 }
 
-func (w *AsyncLogWriter) writeLog(data []byte) {
+func (w *AsyncLogWriter) writeLog(p *[]byte) {
+	defer releaseBytes(p)
 	if w.w != nil {
-		_, _ = w.w.Write(data)
+		_, err := w.w.Write(*p)
+		if err != nil {
+			atomic.AddUint64(&w.writeErrors, 1)
+			if w.conf.OnError != nil {
+				w.conf.OnError(err)
+			}
+			return
+		}
+		atomic.AddUint64(&w.written, 1)
+	}
+}
+
+// Stats 获得统计数据（线程安全）
+func (w *AsyncLogWriter) Stats() AsyncStats {
+	return AsyncStats{
+		Enqueued:    atomic.LoadUint64(&w.enqueued),
+		Written:     atomic.LoadUint64(&w.written),
+		Dropped:     atomic.LoadUint64(&w.dropped),
+		WriteErrors: atomic.LoadUint64(&w.writeErrors),
+	}
+}
+
+// Flush 等待已接收的数据写入实际的Writer（线程安全），如果实际的Writer实现了Flusher则同时调用其Flush
+func (w *AsyncLogWriter) Flush() error {
+	return w.exec(func() error {
+		size := len(w.logChan)
+		for i := 0; i < size; i++ {
+			w.writeLog(<-w.logChan)
+		}
+		if f, ok := w.w.(Flusher); ok {
+			return f.Flush()
+		}
+		return nil
+	})
+}
+
+// exec 在写入协程中执行f，保证与写入操作串行
+func (w *AsyncLogWriter) exec(f func() error) error {
+	errChan := make(chan error, 1)
+	select {
+	case w.cmdChan <- func() { errChan <- f() }:
+		return <-errChan
+	case <-w.stopChan:
+		return ErrWriterClosed
 	}
 }
 
+// Reopen 在写入协程中将已接收的数据写入后调用实际写入Writer的Reopen方法并返回错误（线程安全），
+// 实际写入的Writer需实现Reopener
+func (w *AsyncLogWriter) Reopen() error {
+	r, ok := w.w.(Reopener)
+	if !ok {
+		return errors.New("writer cannot reopen")
+	}
+	return w.exec(func() error {
+		size := len(w.logChan)
+		for i := 0; i < size; i++ {
+			w.writeLog(<-w.logChan)
+		}
+		return r.Reopen()
+	})
+}
+
+// Close 停止接收数据，并在DrainTimeout内将队列中的数据写入
 func (w *AsyncLogWriter) Close() error {
 	w.once.Do(func() {
 		close(w.stopChan)
+		w.lock.Lock()
+		w.closed = true
+		w.lock.Unlock()
+		close(w.quitChan)
 		w.wait.Wait()
 	})
 	return nil
 }
 
+// Write 将data的拷贝放入异步队列，调用者在返回后可以复用data
 func (w *AsyncLogWriter) Write(data []byte) (n int, err error) {
 	if len(data) == 0 {
 		return 0, nil
 	}
 
-	if w.block {
-		w.logChan <- data
-		return len(data), nil
-	} else {
+	w.lock.RLock()
+	defer w.lock.RUnlock()
+	if w.closed {
+		return 0, ErrWriterClosed
+	}
+
+	p := copyBytes(data)
+	switch w.conf.Policy {
+	case PolicyBlock:
+		select {
+		case w.logChan <- p:
+		case <-w.stopChan:
+			releaseBytes(p)
+			return 0, ErrWriterClosed
+		}
+	case PolicyBlockTimeout:
+		timer := time.NewTimer(w.conf.BlockTimeout)
+		defer timer.Stop()
+		select {
+		case w.logChan <- p:
+		case <-w.stopChan:
+			releaseBytes(p)
+			return 0, ErrWriterClosed
+		case <-timer.C:
+			releaseBytes(p)
+			atomic.AddUint64(&w.dropped, 1)
+			return 0, ErrDropped
+		}
+	case PolicyDropOldest:
+		for {
+			select {
+			case w.logChan <- p:
+			default:
+				if cap(w.logChan) == 0 {
+					releaseBytes(p)
+					atomic.AddUint64(&w.dropped, 1)
+					return 0, ErrDropped
+				}
+				select {
+				case old := <-w.logChan:
+					releaseBytes(old)
+					atomic.AddUint64(&w.dropped, 1)
+				default:
+				}
+				continue
+			}
+			break
+		}
+	default:
 		select {
-		case w.logChan <- data:
-			return len(data), nil
+		case w.logChan <- p:
 		default:
-			return 0, errors.New("write log failed ")
+			releaseBytes(p)
+			atomic.AddUint64(&w.dropped, 1)
+			return 0, ErrDropped
 		}
 	}
+	atomic.AddUint64(&w.enqueued, 1)
+	return len(data), nil
 }
@@ -0,0 +1,44 @@
+/*
+ * Copyright (c) 2022, AcmeStack
+ * All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package writer
+
+import "sync"
+
+// 超过该大小的缓存不放回对象池
+const maxPooledSize = 64 * 1024
+
+var bytesPool = sync.Pool{
+	New: func() interface{} {
+		b := make([]byte, 0, 256)
+		return &b
+	},
+}
+
+// copyBytes 从对象池获得data的拷贝，使调用者在Write返回后可以复用data，使用完毕后需调用releaseBytes归还
+func copyBytes(data []byte) *[]byte {
+	p := bytesPool.Get().(*[]byte)
+	*p = append((*p)[:0], data...)
+	return p
+}
+
+func releaseBytes(p *[]byte) {
+	if p == nil || cap(*p) > maxPooledSize {
+		return
+	}
+	bytesPool.Put(p)
+}
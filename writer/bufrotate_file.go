@@ -66,14 +66,19 @@ type BufferedRotateFile struct {
 	RotateFrequency RotateFrequency
 	// 滚动文件处理
 	RotateFunc func(dir string, name string, files ...string) error
+	// 检查文件是否被外部移动的时间间隔，为0时不检查
+	ReopenCheckInterval time.Duration
 
 	// 滚动的时间格式
 	timeFormat string
 	// 滚动的时间间隔
 	rotateDuration time.Duration
 
+	lastCheck time.Time
+
 	stopChan chan struct{}
-	logChan  chan []byte
+	logChan  chan *[]byte
+	cmdChan  chan func()
 	block    bool
 	wait     sync.WaitGroup
 	once     sync.Once
@@ -91,16 +96,17 @@ type BufferedRotateFile struct {
 }
 
 func (f *BufferedRotateFile) Open(conf Config) error {
-	var logChan chan []byte
+	var logChan chan *[]byte
 	// Channel without buffer
 	if conf.BufferSize <= 0 {
-		logChan = make(chan []byte)
+		logChan = make(chan *[]byte)
 	} else {
-		logChan = make(chan []byte, conf.BufferSize)
+		logChan = make(chan *[]byte, conf.BufferSize)
 	}
 	f.block = conf.Block
 	f.logChan = logChan
 	f.stopChan = make(chan struct{})
+	f.cmdChan = make(chan func())
 
 	if f.MaxFileSize == 0 {
 		// no limit
@@ -123,15 +129,11 @@ func (f *BufferedRotateFile) Open(conf Config) error {
 	f.dir = dir
 	f.fileName = filepath.Base(f.Path)
 
-	f.file, err = os.OpenFile(f.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	f.file, f.curSize, err = openLogFile(f.Path)
 	if err != nil {
 		return err
 	}
-	info, err := f.file.Stat()
-	if err != nil {
-		return err
-	}
-	f.curSize = info.Size()
+	f.lastCheck = time.Now()
 	if f.RotateFrequency != RotateNone {
 		f.setFrequency(f.RotateFrequency)
 		f.setTimer()
@@ -146,7 +148,7 @@ func (f *BufferedRotateFile) Open(conf Config) error {
 			defer func() {
 				size := len(f.logChan)
 				for i := 0; i < size; i++ {
-					_, _ = f.tryWrite(<-f.logChan)
+					f.writeBuffer(<-f.logChan)
 				}
 				_, _ = f.writeFile()
 			}()
@@ -156,15 +158,19 @@ func (f *BufferedRotateFile) Open(conf Config) error {
 					return
 				case d, ok := <-f.logChan:
 					if ok {
-						_, _ = f.tryWrite(d)
+						f.writeBuffer(d)
 					}
+				case cmd := <-f.cmdChan:
+					cmd()
 				case <-ticker.C:
+					_ = f.checkReopen()
 					_, _ = f.writeFile()
 				}
 				select {
 				case <-f.stopChan:
 					return
 				case <-ticker.C:
+					_ = f.checkReopen()
 					_, _ = f.writeFile()
 				default:
 				}
@@ -184,28 +190,46 @@ func (f *BufferedRotateFile) setTimer() {
 	f.timer = time.NewTimer(duration)
 }
 
+// Write 将data的拷贝放入异步队列，调用者在返回后可以复用data
 func (f *BufferedRotateFile) Write(data []byte) (int, error) {
 	if len(data) == 0 {
 		return 0, nil
 	}
 
+	p := copyBytes(data)
 	if f.block {
-		f.logChan <- data
+		f.logChan <- p
 		return len(data), nil
 	} else {
 		select {
-		case f.logChan <- data:
+		case f.logChan <- p:
 			return len(data), nil
 		default:
-			return 0, errors.New("write log failed ")
+			releaseBytes(p)
+			return 0, ErrDropped
 		}
 	}
 }
 
+func (f *BufferedRotateFile) writeBuffer(p *[]byte) {
+	_, _ = f.tryWrite(*p)
+	releaseBytes(p)
+}
+
+// tryWrite 写入缓存，自动重新打开文件失败时仍写入原文件，写入成功时返回重新打开的错误
 func (f *BufferedRotateFile) tryWrite(data []byte) (int, error) {
 	if len(data) == 0 {
 		return 0, nil
 	}
+	reopenErr := f.checkReopen()
+	n, err := f.write(data)
+	if err == nil {
+		err = reopenErr
+	}
+	return n, err
+}
+
+func (f *BufferedRotateFile) write(data []byte) (int, error) {
 	berr, n := f.buf.Write(data)
 
 	if f.timer != nil {
@@ -230,6 +254,67 @@ func (f *BufferedRotateFile) tryWrite(data []byte) (int, error) {
 	return berr, n
 }
 
+// Flush 将已接收及缓存的数据写入文件（线程安全）
+func (f *BufferedRotateFile) Flush() error {
+	return f.exec(func() error {
+		_, err := f.writeFile()
+		return err
+	})
+}
+
+// Reopen 由写入协程先将已接收及缓存的数据写入原文件，再重新打开日志文件并返回错误，
+// 打开失败时继续写入原文件（线程安全）
+func (f *BufferedRotateFile) Reopen() error {
+	return f.exec(f.reopen)
+}
+
+// exec 在写入协程中先写入已接收的数据再执行cmd，保证与写入操作串行
+func (f *BufferedRotateFile) exec(cmd func() error) error {
+	if f.cmdChan == nil {
+		return errors.New("file not opened. ")
+	}
+	errChan := make(chan error, 1)
+	fn := func() {
+		size := len(f.logChan)
+		for i := 0; i < size; i++ {
+			f.writeBuffer(<-f.logChan)
+		}
+		errChan <- cmd()
+	}
+	select {
+	case f.cmdChan <- fn:
+		return <-errChan
+	case <-f.stopChan:
+		return ErrWriterClosed
+	}
+}
+
+func (f *BufferedRotateFile) checkReopen() error {
+	if f.ReopenCheckInterval > 0 {
+		now := time.Now()
+		if now.Sub(f.lastCheck) >= f.ReopenCheckInterval {
+			f.lastCheck = now
+			if fileMoved(f.Path, f.file) {
+				return f.reopen()
+			}
+		}
+	}
+	return nil
+}
+
+func (f *BufferedRotateFile) reopen() error {
+	_, _ = f.writeFile()
+	file, size, err := openLogFile(f.Path)
+	if err != nil {
+		return err
+	}
+	if f.file != nil {
+		_ = f.file.Close()
+	}
+	f.file, f.curSize = file, size
+	return nil
+}
+
 func (f *BufferedRotateFile) writeFile() (int, error) {
 	if f.file == nil {
 		return 0, errors.New("file not opened. ")
@@ -0,0 +1,323 @@
+/*
+ * Copyright (c) 2022, AcmeStack
+ * All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package writer
+
+import (
+	"errors"
+	"github.com/acmestack/log4go/util"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// NoLevel 通过Write（而不是WriteLevel）写入时传递给Predicate的级别
+const NoLevel int32 = -1
+
+// LevelWriter 可感知日志级别的Writer，Logging写入时会优先调用WriteLevel
+type LevelWriter interface {
+	io.Writer
+	WriteLevel(level int32, data []byte) (int, error)
+}
+
+// RecordWriter 可获得结构化日志记录的Writer，与logfactory.RecordWriter相同，Logging写入时会优先调用WriteRecord。
+// Tee、Failover、LevelRouter、Conditional均实现了RecordWriter，并将日志记录传递给其中的RecordWriter
+type RecordWriter interface {
+	io.Writer
+	// WriteRecord record包括内置的LogTime、LogLevel等及附加信息（不可修改，需保存时使用Clone），data为格式化后的日志
+	WriteRecord(level int32, record util.KeyValues, data []byte) (int, error)
+}
+
+// MultiError 多个Writer返回的错误
+type MultiError []error
+
+func (e MultiError) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+func (e MultiError) Unwrap() []error {
+	return e
+}
+
+func (e MultiError) errorOrNil() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
+
+// Tee 将数据写入所有的Writer，汇总所有Writer返回的错误
+type Tee struct {
+	writers []io.Writer
+}
+
+func NewTee(ws ...io.Writer) *Tee {
+	return &Tee{writers: ws}
+}
+
+func (t *Tee) Write(data []byte) (int, error) {
+	return t.WriteLevel(NoLevel, data)
+}
+
+func (t *Tee) WriteLevel(level int32, data []byte) (int, error) {
+	return t.WriteRecord(level, nil, data)
+}
+
+func (t *Tee) WriteRecord(level int32, record util.KeyValues, data []byte) (int, error) {
+	var errs MultiError
+	for _, w := range t.writers {
+		if _, err := writeRecord(w, level, record, data); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return len(data), errs.errorOrNil()
+}
+
+func (t *Tee) Flush() error {
+	return flushAll(t.writers)
+}
+
+func (t *Tee) Close() error {
+	return closeAll(t.writers)
+}
+
+// Failover 按顺序写入Writer，直到有一个Writer写入成功，全部失败时返回所有的错误
+type Failover struct {
+	writers []io.Writer
+}
+
+func NewFailover(primary io.Writer, secondaries ...io.Writer) *Failover {
+	return &Failover{writers: append([]io.Writer{primary}, secondaries...)}
+}
+
+func (f *Failover) Write(data []byte) (int, error) {
+	return f.WriteLevel(NoLevel, data)
+}
+
+func (f *Failover) WriteLevel(level int32, data []byte) (int, error) {
+	return f.WriteRecord(level, nil, data)
+}
+
+func (f *Failover) WriteRecord(level int32, record util.KeyValues, data []byte) (int, error) {
+	var errs MultiError
+	for _, w := range f.writers {
+		n, err := writeRecord(w, level, record, data)
+		if err == nil {
+			return n, nil
+		}
+		errs = append(errs, err)
+	}
+	return 0, errs
+}
+
+func (f *Failover) Flush() error {
+	return flushAll(f.writers)
+}
+
+func (f *Failover) Close() error {
+	return closeAll(f.writers)
+}
+
+// LevelRouter 根据日志级别选择Writer，未配置的级别及通过Write写入的数据使用默认Writer
+// 可作为Logging的输出，也可以在Logging之外直接调用WriteLevel
+type LevelRouter struct {
+	def    io.Writer
+	routes sync.Map
+}
+
+func NewLevelRouter(def io.Writer) *LevelRouter {
+	return &LevelRouter{def: def}
+}
+
+// Route 配置级别对应的Writer（线程安全）
+func (r *LevelRouter) Route(level int32, w io.Writer) *LevelRouter {
+	r.routes.Store(level, w)
+	return r
+}
+
+func (r *LevelRouter) Write(data []byte) (int, error) {
+	return r.WriteLevel(NoLevel, data)
+}
+
+func (r *LevelRouter) WriteLevel(level int32, data []byte) (int, error) {
+	return r.WriteRecord(level, nil, data)
+}
+
+func (r *LevelRouter) WriteRecord(level int32, record util.KeyValues, data []byte) (int, error) {
+	if v, ok := r.routes.Load(level); ok {
+		return writeRecord(v.(io.Writer), level, record, data)
+	}
+	if r.def == nil {
+		return len(data), nil
+	}
+	return writeRecord(r.def, level, record, data)
+}
+
+func (r *LevelRouter) writers() []io.Writer {
+	var ret []io.Writer
+	if r.def != nil {
+		ret = append(ret, r.def)
+	}
+	r.routes.Range(func(key, value interface{}) bool {
+		ret = append(ret, value.(io.Writer))
+		return true
+	})
+	return ret
+}
+
+func (r *LevelRouter) Flush() error {
+	return flushAll(r.writers())
+}
+
+func (r *LevelRouter) Close() error {
+	return closeAll(r.writers())
+}
+
+// Predicate 判断是否写入，level为日志级别，通过Write写入时为NoLevel
+type Predicate func(level int32, data []byte) bool
+
+// RecordPredicate 根据结构化日志记录判断是否写入，record为Logging传递的日志记录（不可修改），
+// 通过Write、WriteLevel写入时为nil
+type RecordPredicate func(level int32, record util.KeyValues, data []byte) bool
+
+// ErrNilPredicate 创建Conditional时Predicate为nil
+var ErrNilPredicate = errors.New("Conditional predicate is nil ")
+
+// MinLevel 返回仅写入严重程度不低于level的Predicate，通过Write写入的数据总是写入
+func MinLevel(level int32) Predicate {
+	return func(l int32, data []byte) bool {
+		return l == NoLevel || l <= level
+	}
+}
+
+// Conditional 当Predicate返回true时写入Writer，否则丢弃数据
+type Conditional struct {
+	w         io.Writer
+	predicate RecordPredicate
+}
+
+// NewConditional 创建根据级别及日志内容判断是否写入的Conditional，predicate为nil时panic(ErrNilPredicate)
+func NewConditional(w io.Writer, predicate Predicate) *Conditional {
+	if predicate == nil {
+		panic(ErrNilPredicate)
+	}
+	return NewRecordConditional(w, func(level int32, record util.KeyValues, data []byte) bool {
+		return predicate(level, data)
+	})
+}
+
+// NewRecordConditional 创建根据结构化日志记录判断是否写入的Conditional，predicate为nil时panic(ErrNilPredicate)
+func NewRecordConditional(w io.Writer, predicate RecordPredicate) *Conditional {
+	if predicate == nil {
+		panic(ErrNilPredicate)
+	}
+	return &Conditional{w: w, predicate: predicate}
+}
+
+func (c *Conditional) Write(data []byte) (int, error) {
+	return c.WriteRecord(NoLevel, nil, data)
+}
+
+func (c *Conditional) WriteLevel(level int32, data []byte) (int, error) {
+	return c.WriteRecord(level, nil, data)
+}
+
+func (c *Conditional) WriteRecord(level int32, record util.KeyValues, data []byte) (int, error) {
+	if !c.predicate(level, record, data) {
+		return len(data), nil
+	}
+	return writeRecord(c.w, level, record, data)
+}
+
+func (c *Conditional) Flush() error {
+	return flushAll([]io.Writer{c.w})
+}
+
+func (c *Conditional) Close() error {
+	return closeAll([]io.Writer{c.w})
+}
+
+// writeRecord record不为nil时优先调用RecordWriter.WriteRecord，否则同writeLevel
+func writeRecord(w io.Writer, level int32, record util.KeyValues, data []byte) (int, error) {
+	if rw, ok := w.(RecordWriter); ok && record != nil {
+		return rw.WriteRecord(level, record, data)
+	}
+	return writeLevel(w, level, data)
+}
+
+func writeLevel(w io.Writer, level int32, data []byte) (int, error) {
+	if lw, ok := w.(LevelWriter); ok && level != NoLevel {
+		return lw.WriteLevel(level, data)
+	}
+	return w.Write(data)
+}
+
+func flushAll(ws []io.Writer) error {
+	var errs MultiError
+	for _, w := range distinct(ws) {
+		if f, ok := w.(Flusher); ok {
+			if err := f.Flush(); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errs.errorOrNil()
+}
+
+// closeAll 关闭所有实现io.Closer的Writer，注意不会关闭标准输出及标准错误
+func closeAll(ws []io.Writer) error {
+	var errs MultiError
+	for _, w := range distinct(ws) {
+		if w == io.Writer(os.Stdout) || w == io.Writer(os.Stderr) {
+			continue
+		}
+		if c, ok := w.(io.Closer); ok {
+			if err := c.Close(); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errs.errorOrNil()
+}
+
+func distinct(ws []io.Writer) []io.Writer {
+	ret := make([]io.Writer, 0, len(ws))
+	for _, w := range ws {
+		if w == nil {
+			continue
+		}
+		dup := false
+		if reflect.TypeOf(w).Comparable() {
+			for _, v := range ret {
+				if reflect.TypeOf(v) == reflect.TypeOf(w) && v == w {
+					dup = true
+					break
+				}
+			}
+		}
+		if !dup {
+			ret = append(ret, w)
+		}
+	}
+	return ret
+}
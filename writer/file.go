@@ -0,0 +1,128 @@
+/*
+ * Copyright (c) 2022, AcmeStack
+ * All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package writer
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+func NewFileWriter(f *File, conf ...Config) io.WriteCloser {
+	if f == nil {
+		return nil
+	}
+
+	err := f.Open()
+	if err != nil {
+		return nil
+	}
+
+	return NewAsyncBufferWriter(f, f.Close, conf...)
+}
+
+// File 不滚动的日志文件，支持配合logrotate等外部工具重新打开文件（线程安全）
+type File struct {
+	//文件路径
+	Path string
+	// 检查文件是否被外部移动的时间间隔，为0时不检查
+	ReopenCheckInterval time.Duration
+
+	lock      sync.Mutex
+	lastCheck time.Time
+	file      *os.File
+}
+
+func (f *File) Open() error {
+	dir := filepath.Dir(f.Path)
+	_, err := os.Stat(dir)
+	if err != nil {
+		err = os.Mkdir(dir, os.ModePerm)
+		if err != nil {
+			return err
+		}
+	}
+
+	f.file, _, err = openLogFile(f.Path)
+	if err != nil {
+		return err
+	}
+	f.lastCheck = time.Now()
+	return nil
+}
+
+// Write 写入日志文件，自动重新打开文件失败时仍写入原文件，写入成功时返回重新打开的错误
+func (f *File) Write(data []byte) (int, error) {
+	if len(data) == 0 {
+		return 0, nil
+	}
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	reopenErr := f.checkReopen()
+	n, err := f.file.Write(data)
+	if err == nil {
+		err = reopenErr
+	}
+	return n, err
+}
+
+// Reopen 立即重新打开日志文件并返回错误，打开失败时继续写入原文件（线程安全）
+func (f *File) Reopen() error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	return f.reopen()
+}
+
+func (f *File) checkReopen() error {
+	if f.ReopenCheckInterval > 0 {
+		now := time.Now()
+		if now.Sub(f.lastCheck) >= f.ReopenCheckInterval {
+			f.lastCheck = now
+			if fileMoved(f.Path, f.file) {
+				return f.reopen()
+			}
+		}
+	}
+	return nil
+}
+
+func (f *File) reopen() error {
+	file, _, err := openLogFile(f.Path)
+	if err != nil {
+		return err
+	}
+	if f.file != nil {
+		_ = f.file.Close()
+	}
+	f.file = file
+	return nil
+}
+
+func (f *File) Close() error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if f.file != nil {
+		return f.file.Close()
+	}
+	return nil
+}
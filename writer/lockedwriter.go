@@ -36,6 +36,25 @@ func (lw *LockedWriter) Write(d []byte) (int, error) {
 	return lw.W.Write(d)
 }
 
+// Flush 如果W实现了Flusher则调用其Flush
+func (lw *LockedWriter) Flush() error {
+	lw.lock.Lock()
+	defer lw.lock.Unlock()
+
+	if f, ok := lw.W.(Flusher); ok {
+		return f.Flush()
+	}
+	return nil
+}
+
+// WriteLevel 如果W实现了LevelWriter则调用其WriteLevel，否则调用Write
+func (lw *LockedWriter) WriteLevel(level int32, d []byte) (int, error) {
+	lw.lock.Lock()
+	defer lw.lock.Unlock()
+
+	return writeLevel(lw.W, level, d)
+}
+
 type LockedWriteCloser struct {
 	lock sync.Mutex
 	W    io.WriteCloser
@@ -54,3 +73,22 @@ func (lw *LockedWriteCloser) Close() error {
 
 	return lw.W.Close()
 }
+
+// Flush 如果W实现了Flusher则调用其Flush
+func (lw *LockedWriteCloser) Flush() error {
+	lw.lock.Lock()
+	defer lw.lock.Unlock()
+
+	if f, ok := lw.W.(Flusher); ok {
+		return f.Flush()
+	}
+	return nil
+}
+
+// WriteLevel 如果W实现了LevelWriter则调用其WriteLevel，否则调用Write
+func (lw *LockedWriteCloser) WriteLevel(level int32, d []byte) (int, error) {
+	lw.lock.Lock()
+	defer lw.lock.Unlock()
+
+	return writeLevel(lw.W, level, d)
+}
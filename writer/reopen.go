@@ -0,0 +1,98 @@
+/*
+ * Copyright (c) 2022, AcmeStack
+ * All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package writer
+
+import (
+	"os"
+	"os/signal"
+)
+
+// Reopener 可重新打开日志文件的Writer，用于配合logrotate等外部工具移动日志文件后继续写入新文件
+type Reopener interface {
+	// Reopen 重新打开日志文件并返回错误（线程安全），缓存的数据会先写入原文件
+	Reopen() error
+}
+
+// ReopenOnSignal 监听信号，收到信号时调用所有Reopener的Reopen方法，忽略Reopen返回的错误
+// Param: sigs - 监听的信号，为空时使用DefaultReopenSignals，rs - 需要重新打开的Writer
+// Return: 停止监听的函数
+func ReopenOnSignal(sigs []os.Signal, rs ...Reopener) (stop func()) {
+	return ReopenOnSignalWithHandler(sigs, nil, rs...)
+}
+
+// ReopenOnSignalWithHandler 同ReopenOnSignal，每个Reopener重新打开后以其Reopen的返回值调用handler
+// Param: sigs - 监听的信号，为空时使用DefaultReopenSignals，handler - 可为nil，rs - 需要重新打开的Writer
+// Return: 停止监听的函数
+func ReopenOnSignalWithHandler(sigs []os.Signal, handler func(r Reopener, err error), rs ...Reopener) (stop func()) {
+	if len(sigs) == 0 {
+		sigs = DefaultReopenSignals
+	}
+	sigChan := make(chan os.Signal, 1)
+	stopChan := make(chan struct{})
+	signal.Notify(sigChan, sigs...)
+
+	go func() {
+		for {
+			select {
+			case <-stopChan:
+				return
+			case <-sigChan:
+				for _, r := range rs {
+					err := r.Reopen()
+					if handler != nil {
+						handler(r, err)
+					}
+				}
+			}
+		}
+	}()
+
+	return func() {
+		signal.Stop(sigChan)
+		close(stopChan)
+	}
+}
+
+func openLogFile(path string) (*os.File, int64, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, 0, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return nil, 0, err
+	}
+	return file, info.Size(), nil
+}
+
+// fileMoved 判断path对应的文件是否已不是file（被移动或删除）
+func fileMoved(path string, file *os.File) bool {
+	if file == nil {
+		return false
+	}
+	pathInfo, err := os.Stat(path)
+	if err != nil {
+		return true
+	}
+	fileInfo, err := file.Stat()
+	if err != nil {
+		return true
+	}
+	return !os.SameFile(pathInfo, fileInfo)
+}
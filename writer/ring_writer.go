@@ -0,0 +1,458 @@
+/*
+ * Copyright (c) 2022, AcmeStack
+ * All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package writer
+
+import (
+	"io"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	RingSlots     = 4096
+	RingSlotSize  = 256
+	RingBatchSize = 64 * 1024
+)
+
+type RingConfig struct {
+	// 环形队列的槽数量，会向上取整为2的幂，为0时使用RingSlots
+	Slots int
+
+	// 每个槽预分配的字节数，超出时该槽会重新分配，为0时使用RingSlotSize
+	SlotSize int
+
+	// 单次批量写入实际Writer的最大字节数，为0时使用RingBatchSize
+	BatchSize int
+
+	// 队列已满时的处理策略，PolicyBlock、PolicyBlockTimeout时写入者阻塞等待写入协程释放槽。
+	// PolicyDropOldest时由写入协程丢弃队列中最早的数据，写入者需等待写入协程完成当前批次的写入
+	Policy DropPolicy
+
+	// Policy为PolicyBlockTimeout时的最长阻塞时间，小于等于0时使用BlockTime
+	BlockTimeout time.Duration
+
+	// 队列为空时写入协程的最长等待时间，为0时使用FlushTime
+	FlushInterval time.Duration
+
+	// Close时等待队列中数据写入的超时时间，为0时使用DrainTime，小于0时不等待直接丢弃队列中的数据
+	DrainTimeout time.Duration
+
+	// 实际Writer写入失败时的回调，在写入协程中调用
+	OnError func(err error)
+}
+
+type ringSlot struct {
+	seq  uint64
+	data []byte
+}
+
+// RingBufferWriter 基于预分配环形队列的无锁异步Writer，多个协程写入，单个协程将多个槽的数据合并后
+// 一次写入实际的Writer。本身Write、Flush、Close方法线程安全，参数Writer可以非线程安全
+type RingBufferWriter struct {
+	// head、tail分别由写入者及写入协程修改，填充避免伪共享
+	head uint64
+	_    [56]byte
+	tail uint64
+	_    [56]byte
+
+	slots   []ringSlot
+	mask    uint64
+	batch   []byte
+	waiting int32
+	closed  int32
+	// inflight 正在执行Write的数量，Close后写入协程等待其归零，保证成功返回的数据被写入或计入丢弃数量
+	inflight int64
+	// dropRequests PolicyDropOldest时写入者请求丢弃的数量
+	dropRequests int64
+	// spaceWaiters 等待空闲槽的写入者数量，space在写入协程释放槽后关闭并替换，唤醒所有等待的写入者
+	spaceWaiters int32
+	spaceLock    sync.Mutex
+	space        chan struct{}
+	notify       chan struct{}
+	cmdChan      chan func()
+	stopChan     chan struct{}
+	w            io.Writer
+	conf         RingConfig
+	wait         sync.WaitGroup
+	once         sync.Once
+
+	enqueued    uint64
+	written     uint64
+	dropped     uint64
+	writeErrors uint64
+}
+
+// NewRingBufferWriter 创建基于环形队列的异步Writer
+// Param： w - 实际写入的Writer, closer - 写入协程退出时调用, c - Writer的配置，如果不传入则使用默认值，否则使用第1个配置
+func NewRingBufferWriter(w io.Writer, closer Closer, c ...RingConfig) *RingBufferWriter {
+	var conf RingConfig
+	if len(c) > 0 {
+		conf = c[0]
+	}
+	if conf.Slots <= 0 {
+		conf.Slots = RingSlots
+	}
+	if conf.SlotSize <= 0 {
+		conf.SlotSize = RingSlotSize
+	}
+	if conf.BatchSize <= 0 {
+		conf.BatchSize = RingBatchSize
+	}
+	if conf.FlushInterval <= 0 {
+		conf.FlushInterval = FlushTime
+	}
+	if conf.DrainTimeout == 0 {
+		conf.DrainTimeout = DrainTime
+	}
+	if conf.Policy == PolicyBlockTimeout && conf.BlockTimeout <= 0 {
+		conf.BlockTimeout = BlockTime
+	}
+	size := 1
+	for size < conf.Slots {
+		size <<= 1
+	}
+
+	r := &RingBufferWriter{
+		slots:    make([]ringSlot, size),
+		mask:     uint64(size - 1),
+		batch:    make([]byte, 0, conf.BatchSize),
+		space:    make(chan struct{}),
+		notify:   make(chan struct{}, 1),
+		cmdChan:  make(chan func()),
+		stopChan: make(chan struct{}),
+		w:        w,
+		conf:     conf,
+	}
+	for i := range r.slots {
+		r.slots[i].seq = uint64(i)
+		r.slots[i].data = make([]byte, 0, conf.SlotSize)
+	}
+
+	r.wait.Add(1)
+	go func() {
+		defer r.wait.Done()
+		if closer != nil {
+			defer closer()
+		}
+		ticker := time.NewTicker(conf.FlushInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case cmd := <-r.cmdChan:
+				cmd()
+			default:
+			}
+			if r.consume() > 0 {
+				continue
+			}
+			atomic.StoreInt32(&r.waiting, 1)
+			// 避免在设置waiting前写入的数据无法唤醒写入协程
+			if r.ready() {
+				atomic.StoreInt32(&r.waiting, 0)
+				continue
+			}
+			select {
+			case <-r.stopChan:
+				atomic.StoreInt32(&r.waiting, 0)
+				r.drain()
+				return
+			case <-r.notify:
+			case cmd := <-r.cmdChan:
+				cmd()
+			case <-ticker.C:
+			}
+			atomic.StoreInt32(&r.waiting, 0)
+		}
+	}()
+	return r
+}
+
+// Write 将data的拷贝放入环形队列，调用者在返回后可以复用data
+func (r *RingBufferWriter) Write(data []byte) (int, error) {
+	if len(data) == 0 {
+		return 0, nil
+	}
+	// 先计数再检查closed，Close后写入协程会等待计数归零
+	atomic.AddInt64(&r.inflight, 1)
+	defer atomic.AddInt64(&r.inflight, -1)
+	if atomic.LoadInt32(&r.closed) == 1 {
+		return 0, ErrWriterClosed
+	}
+
+	var timeout <-chan time.Time
+	requested := false
+	for {
+		if r.offer(data) {
+			atomic.AddUint64(&r.enqueued, 1)
+			r.wakeup()
+			return len(data), nil
+		}
+		switch r.conf.Policy {
+		case PolicyBlock:
+		case PolicyDropOldest:
+			if !requested {
+				atomic.AddInt64(&r.dropRequests, 1)
+				requested = true
+			}
+		case PolicyBlockTimeout:
+			if timeout == nil {
+				timer := time.NewTimer(r.conf.BlockTimeout)
+				defer timer.Stop()
+				timeout = timer.C
+			}
+		default:
+			atomic.AddUint64(&r.dropped, 1)
+			return 0, ErrDropped
+		}
+		ok, err := r.waitSpace(data, timeout)
+		if ok {
+			atomic.AddUint64(&r.enqueued, 1)
+			r.wakeup()
+			return len(data), nil
+		}
+		if err == ErrDropped {
+			atomic.AddUint64(&r.dropped, 1)
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+}
+
+// waitSpace 阻塞等待写入协程释放槽，返回是否已写入data。Close时返回ErrWriterClosed，timeout到期时返回ErrDropped
+func (r *RingBufferWriter) waitSpace(data []byte, timeout <-chan time.Time) (bool, error) {
+	atomic.AddInt32(&r.spaceWaiters, 1)
+	defer atomic.AddInt32(&r.spaceWaiters, -1)
+	r.spaceLock.Lock()
+	space := r.space
+	r.spaceLock.Unlock()
+	// 获得space后再尝试一次，避免错过在此之前释放槽的通知
+	if r.offer(data) {
+		return true, nil
+	}
+	r.wakeup()
+	select {
+	case <-space:
+		return false, nil
+	case <-r.stopChan:
+		return false, ErrWriterClosed
+	case <-timeout:
+		return false, ErrDropped
+	}
+}
+
+// signalSpace 唤醒等待空闲槽的写入者
+func (r *RingBufferWriter) signalSpace() {
+	if atomic.LoadInt32(&r.spaceWaiters) == 0 {
+		return
+	}
+	r.spaceLock.Lock()
+	close(r.space)
+	r.space = make(chan struct{})
+	r.spaceLock.Unlock()
+}
+
+// offer 尝试占用一个槽并写入数据，队列已满时返回false
+func (r *RingBufferWriter) offer(data []byte) bool {
+	pos := atomic.LoadUint64(&r.head)
+	var slot *ringSlot
+	for {
+		slot = &r.slots[pos&r.mask]
+		seq := atomic.LoadUint64(&slot.seq)
+		diff := int64(seq - pos)
+		if diff == 0 {
+			if atomic.CompareAndSwapUint64(&r.head, pos, pos+1) {
+				break
+			}
+		} else if diff < 0 {
+			return false
+		} else {
+			pos = atomic.LoadUint64(&r.head)
+		}
+	}
+	slot.data = append(slot.data[:0], data...)
+	atomic.StoreUint64(&slot.seq, pos+1)
+	return true
+}
+
+func (r *RingBufferWriter) wakeup() {
+	if atomic.LoadInt32(&r.waiting) == 1 && atomic.CompareAndSwapInt32(&r.waiting, 1, 0) {
+		select {
+		case r.notify <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (r *RingBufferWriter) ready() bool {
+	pos := atomic.LoadUint64(&r.tail)
+	return atomic.LoadUint64(&r.slots[pos&r.mask].seq) == pos+1
+}
+
+// consume 将已写入的数据合并后一次写入实际的Writer，返回处理的数量
+func (r *RingBufferWriter) consume() int {
+	pos := atomic.LoadUint64(&r.tail)
+	discarded := r.discard(pos)
+	pos += uint64(discarded)
+
+	start := pos
+	r.batch = r.batch[:0]
+	for {
+		slot := &r.slots[pos&r.mask]
+		if atomic.LoadUint64(&slot.seq) != pos+1 {
+			break
+		}
+		if len(r.batch) > 0 && len(r.batch)+len(slot.data) > r.conf.BatchSize {
+			break
+		}
+		r.batch = append(r.batch, slot.data...)
+		// 已拷贝到batch，立即释放槽供写入者使用
+		r.release(pos)
+		pos++
+	}
+	count := int(pos - start)
+	if count == 0 {
+		if discarded > 0 {
+			r.signalSpace()
+		}
+		return discarded
+	}
+	r.signalSpace()
+
+	if r.w != nil {
+		_, err := r.w.Write(r.batch)
+		if err != nil {
+			atomic.AddUint64(&r.writeErrors, uint64(count))
+			if r.conf.OnError != nil {
+				r.conf.OnError(err)
+			}
+		} else {
+			atomic.AddUint64(&r.written, uint64(count))
+		}
+	}
+	if cap(r.batch) > 2*r.conf.BatchSize {
+		r.batch = make([]byte, 0, r.conf.BatchSize)
+	}
+	// 写入完成后再更新，Flush以此判断数据已写入
+	atomic.StoreUint64(&r.tail, pos)
+	return discarded + count
+}
+
+// discard 处理PolicyDropOldest的丢弃请求，从pos开始丢弃已写入的数据，返回丢弃的数量
+func (r *RingBufferWriter) discard(pos uint64) int {
+	n := atomic.SwapInt64(&r.dropRequests, 0)
+	count := 0
+	for ; n > 0; n-- {
+		if atomic.LoadUint64(&r.slots[(pos+uint64(count))&r.mask].seq) != pos+uint64(count)+1 {
+			break
+		}
+		r.release(pos + uint64(count))
+		count++
+	}
+	if count > 0 {
+		atomic.AddUint64(&r.dropped, uint64(count))
+		atomic.StoreUint64(&r.tail, pos+uint64(count))
+	}
+	return count
+}
+
+// release 释放pos对应的槽供写入者使用
+func (r *RingBufferWriter) release(pos uint64) {
+	slot := &r.slots[pos&r.mask]
+	if cap(slot.data) > maxPooledSize {
+		slot.data = make([]byte, 0, r.conf.SlotSize)
+	}
+	atomic.StoreUint64(&slot.seq, pos+r.mask+1)
+}
+
+// drain 在DrainTimeout内将队列中的数据写入，超时后剩余的数据计入丢弃数量。
+// 需等待正在执行的Write返回，避免其放入队列的数据既未写入也未计入丢弃数量
+func (r *RingBufferWriter) drain() {
+	if r.conf.DrainTimeout > 0 {
+		deadline := time.Now().Add(r.conf.DrainTimeout)
+		for spin := 0; time.Now().Before(deadline); spin++ {
+			if r.consume() == 0 {
+				if atomic.LoadInt64(&r.inflight) == 0 && atomic.LoadUint64(&r.tail) == atomic.LoadUint64(&r.head) {
+					return
+				}
+				// 写入者已占用槽但尚未完成写入
+				backoff(spin)
+			}
+		}
+	}
+	// Close后阻塞的写入者会返回ErrWriterClosed，等待其全部返回后统计剩余数据
+	for spin := 0; atomic.LoadInt64(&r.inflight) > 0; spin++ {
+		backoff(spin)
+	}
+	atomic.AddUint64(&r.dropped, atomic.LoadUint64(&r.head)-atomic.LoadUint64(&r.tail))
+}
+
+// Flush 等待调用前已放入队列的数据写入实际的Writer（线程安全），如果实际的Writer实现了Flusher则同时调用其Flush
+func (r *RingBufferWriter) Flush() error {
+	head := atomic.LoadUint64(&r.head)
+	for spin := 0; atomic.LoadUint64(&r.tail) < head; spin++ {
+		select {
+		case <-r.stopChan:
+			return ErrWriterClosed
+		default:
+		}
+		r.wakeup()
+		backoff(spin)
+	}
+	f, ok := r.w.(Flusher)
+	if !ok {
+		return nil
+	}
+	errChan := make(chan error, 1)
+	select {
+	case r.cmdChan <- func() { errChan <- f.Flush() }:
+		return <-errChan
+	case <-r.stopChan:
+		return ErrWriterClosed
+	}
+}
+
+// Stats 获得统计数据（线程安全）
+func (r *RingBufferWriter) Stats() AsyncStats {
+	return AsyncStats{
+		Enqueued:    atomic.LoadUint64(&r.enqueued),
+		Written:     atomic.LoadUint64(&r.written),
+		Dropped:     atomic.LoadUint64(&r.dropped),
+		WriteErrors: atomic.LoadUint64(&r.writeErrors),
+	}
+}
+
+// Close 停止接收数据，并在DrainTimeout内将队列中的数据写入
+func (r *RingBufferWriter) Close() error {
+	r.once.Do(func() {
+		atomic.StoreInt32(&r.closed, 1)
+		close(r.stopChan)
+		r.wait.Wait()
+	})
+	return nil
+}
+
+func backoff(spin int) {
+	if spin < 16 {
+		runtime.Gosched()
+	} else {
+		time.Sleep(10 * time.Microsecond)
+	}
+}
@@ -26,6 +26,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -65,12 +66,17 @@ type RotateFile struct {
 	RotateFrequency RotateFrequency
 	// 滚动文件处理
 	RotateFunc func(dir string, name string, files ...string) error
+	// 检查文件是否被外部移动的时间间隔，为0时不检查
+	ReopenCheckInterval time.Duration
 
 	// 滚动的时间格式
 	timeFormat string
 	// 滚动的时间间隔
 	rotateDuration time.Duration
 
+	lock      sync.Mutex
+	lastCheck time.Time
+
 	timer      *time.Timer
 	fileName   string
 	dir        string
@@ -100,15 +106,11 @@ func (f *RotateFile) Open() error {
 	f.dir = dir
 	f.fileName = filepath.Base(f.Path)
 
-	f.file, err = os.OpenFile(f.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
-	if err != nil {
-		return err
-	}
-	info, err := f.file.Stat()
+	f.file, f.curSize, err = openLogFile(f.Path)
 	if err != nil {
 		return err
 	}
-	f.curSize = info.Size()
+	f.lastCheck = time.Now()
 	if f.RotateFrequency != RotateNone {
 		f.setFrequency(f.RotateFrequency)
 		f.setTimer()
@@ -126,10 +128,23 @@ func (f *RotateFile) setTimer() {
 	f.timer = time.NewTimer(duration)
 }
 
+// Write 写入日志文件，自动重新打开文件失败时仍写入原文件，写入成功时返回重新打开的错误
 func (f *RotateFile) Write(data []byte) (int, error) {
 	if len(data) == 0 {
 		return 0, nil
 	}
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	reopenErr := f.checkReopen()
+	n, err := f.write(data)
+	if err == nil {
+		err = reopenErr
+	}
+	return n, err
+}
+
+func (f *RotateFile) write(data []byte) (int, error) {
 	if f.timer != nil {
 		select {
 		case <-f.timer.C:
@@ -155,6 +170,39 @@ func (f *RotateFile) Write(data []byte) (int, error) {
 	return n, err
 }
 
+// Reopen 立即重新打开日志文件并返回错误，打开失败时继续写入原文件（线程安全）
+func (f *RotateFile) Reopen() error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	return f.reopen()
+}
+
+func (f *RotateFile) checkReopen() error {
+	if f.ReopenCheckInterval > 0 {
+		now := time.Now()
+		if now.Sub(f.lastCheck) >= f.ReopenCheckInterval {
+			f.lastCheck = now
+			if fileMoved(f.Path, f.file) {
+				return f.reopen()
+			}
+		}
+	}
+	return nil
+}
+
+func (f *RotateFile) reopen() error {
+	file, size, err := openLogFile(f.Path)
+	if err != nil {
+		return err
+	}
+	if f.file != nil {
+		_ = f.file.Close()
+	}
+	f.file, f.curSize = file, size
+	return nil
+}
+
 func (f *RotateFile) rotateByTime() error {
 	//err := f.changeFile(fmt.Sprintf("%s-%s", f.curTimeStr, f.fileName))
 	err := f.rotatePart()
@@ -245,6 +293,9 @@ func (f *RotateFile) nextTime() time.Time {
 }
 
 func (f *RotateFile) Close() error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
 	if f.timer != nil {
 		f.timer.Stop()
 	}